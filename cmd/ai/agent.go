@@ -0,0 +1,94 @@
+package main
+
+import (
+	"ai/internal/agent"
+	"ai/internal/cli"
+	"ai/internal/config"
+	"ai/internal/provider/ai"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// runAgent lets the model call the built-in tools until it gives a final answer. Every call
+// is shown on stderr and runs after approval: y once, a for every later call of the same tool
+// or command line. --yes approves the read-only tools, commands are always confirmed. Denied
+// and failed calls are reported back to the model.
+func runAgent(ctx context.Context, model ai.Provider, flags *cli.CMDFlags, cfg *config.Config, input string) (string, error) {
+	timeout := time.Duration(cfg.Agent.CommandTimeoutSeconds) * time.Second
+	toolbox, err := agent.New(cfg.InputFileLimitKB, cfg.InputDocumentLimitKB, cfg.Agent.AllowedCommands, timeout)
+	if err != nil {
+		return "", err
+	}
+	tools := toolbox.Tools()
+
+	// Approvals take longer than a request, only the requests are timed
+	base := context.WithoutCancel(ctx)
+	approved := map[string]bool{}
+	messages := []ai.ChatMessage{{Role: ai.RoleUser, Text: strings.TrimRight(cfg.Prompts.Agent, "\n") + "\n\n" + input}}
+	for step := 0; step < cfg.Agent.MaxSteps; step++ {
+		stepCtx, cancel := context.WithTimeout(base, time.Duration(cfg.HttpTimeoutSeconds)*time.Second)
+		reply, err := model.Chat(stepCtx, messages, tools)
+		cancel()
+		if err != nil {
+			return "", err
+		}
+		if len(reply.Calls) == 0 {
+			return reply.Text, nil
+		}
+		if text := strings.TrimSpace(reply.Text); text != "" {
+			infof(ctx, "%s", text)
+		}
+		messages = append(messages, reply)
+
+		results := ai.ChatMessage{Role: ai.RoleUser}
+		for _, call := range reply.Calls {
+			result, err := runToolCall(base, toolbox, call, flags.Yes, approved)
+			if err != nil {
+				return "", err
+			}
+			results.Results = append(results.Results, result)
+		}
+		messages = append(messages, results)
+	}
+	return "", fmt.Errorf("no final answer after %d steps (agent.maxSteps)", cfg.Agent.MaxSteps)
+}
+
+// runToolCall returns the result of a call for the model, the error is set when the approval
+// could not be asked
+func runToolCall(ctx context.Context, toolbox *agent.Toolbox, call ai.ToolCall, yes bool, approved map[string]bool) (ai.ToolResult, error) {
+	result := ai.ToolResult{CallID: call.ID, Name: call.Name}
+	fail := func(err error) (ai.ToolResult, error) {
+		fmt.Fprintf(os.Stderr, "  %v\n", err)
+		result.Output, result.IsError = err.Error(), true
+		return result, nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Tool call: %s\n", agent.Describe(call))
+	if err := toolbox.Check(call); err != nil {
+		return fail(err)
+	}
+	key := agent.ApprovalKey(call)
+	if !(yes && agent.ReadOnly(call)) && !approved[key] {
+		answer, err := cli.Ask("Allow? [y/N/a(lways for " + key + ")]: ")
+		if err != nil {
+			return result, err
+		}
+		switch strings.ToLower(answer) {
+		case "a", "always":
+			approved[key] = true
+		case "y", "yes":
+		default:
+			return fail(fmt.Errorf("the user denied this call"))
+		}
+	}
+
+	output, err := toolbox.Run(ctx, call)
+	if err != nil {
+		return fail(err)
+	}
+	result.Output = output
+	return result, nil
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"ai/internal/batch"
+	"ai/internal/cli"
+	"ai/internal/config"
+	"ai/internal/provider/ai"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// runBatch runs the selected operation over every input given as positional arguments:
+// files, directories, globs or .jsonl files of {"id", "input"} records.
+func runBatch(flags *cli.CMDFlags, cfg *config.Config) error {
+	if len(flags.Args) == 0 {
+		return fmt.Errorf("missing inputs, usage: ai batch [flags] <files|globs|records.jsonl>...")
+	}
+	if flags.OutDir == "" && flags.Results == "" && flags.Output != outputJSONL {
+		return fmt.Errorf("batch requires --out-dir, --results or --output jsonl")
+	}
+
+	items, err := loadBatchItems(flags.Args)
+	if err != nil {
+		return err
+	}
+
+	if err := checkOperationFlags(flags, cfg); err != nil {
+		return err
+	}
+	if flags.Diff || flags.InPlace {
+		return fmt.Errorf("--diff and --in-place are not supported in batch mode")
+	}
+
+	terms, err := loadGlossary(context.Background(), flags, cfg)
+	if err != nil {
+		return err
+	}
+
+	model, err := newProvider(flags.Provider, cfg)
+	if err != nil {
+		return err
+	}
+	applyOptions(model, flags, terms)
+
+	outPaths := batch.OutputPaths(flags.OutDir, items)
+
+	// Resume: skip items already completed by a previous run
+	completed := map[string]bool{}
+	if flags.Resume && flags.Results != "" {
+		if completed, err = batch.Completed(flags.Results); err != nil {
+			return fmt.Errorf("failed to read previous results: %w", err)
+		}
+	}
+	var pending []batch.Item
+	for _, item := range items {
+		if flags.Resume && flags.OutDir != "" {
+			if out, ok := outPaths[item.ID]; ok {
+				if _, err := os.Stat(out); err == nil {
+					completed[item.ID] = true
+				}
+			}
+		}
+		if !completed[item.ID] {
+			pending = append(pending, item)
+		}
+	}
+
+	var results *os.File
+	if flags.Results != "" {
+		mode := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		if flags.Resume {
+			mode = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		}
+		if err := os.MkdirAll(filepath.Dir(flags.Results), 0755); err != nil {
+			return fmt.Errorf("error creating directory: %w", err)
+		}
+		if results, err = os.OpenFile(flags.Results, mode, 0644); err != nil {
+			return fmt.Errorf("failed to open results file: %w", err)
+		}
+		defer results.Close()
+	}
+
+	// Ctrl+C stops handing out new items, finished ones are kept for --resume
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if flags.Output == outputJSONL {
+		ctx, _ = collectWarnings(ctx) // keeps progress notes out of stderr
+	}
+
+	// Usage, latency and warnings of each item, for its --output jsonl envelope
+	type itemReport struct {
+		usage    *ai.Usage
+		warnings *warningLog
+		latency  time.Duration
+	}
+	var reports sync.Map
+
+	workers := flags.Workers
+	if workers == 0 {
+		workers = cfg.Batch.Workers
+	}
+	opts := batch.Options{Workers: workers}
+
+	process := func(ctx context.Context, item batch.Item) (string, error) {
+		start := time.Now()
+		ctx, usage := ai.TrackUsage(ctx)
+		var warnings *warningLog
+		if flags.Output == outputJSONL {
+			ctx, warnings = collectWarnings(ctx)
+		}
+		defer func() {
+			reports.Store(item.ID, itemReport{usage: usage, warnings: warnings, latency: time.Since(start)})
+		}()
+
+		input := item.Input
+		if item.Path != "" {
+			content, err := cli.ReadFile(item.Path, cfg.InputFileLimitKB, cfg.InputDocumentLimitKB, extractHTML(flags))
+			if err != nil {
+				return "", err
+			}
+			input = content
+		}
+		if flags.Input != "" {
+			input = flags.Input + "\n" + input
+		}
+		if flags.IsExplain {
+			input = explainInput(ctx, flags, cfg, input)
+		}
+
+		// Markdown documents time each of their requests
+		var output string
+		var err error
+		if markdownItem(flags, item) {
+			output, err = processMarkdown(ctx, input, cfg, func(ctx context.Context, text string) (string, error) {
+				if flags.IsRewrite {
+					return model.Rewrite(ctx, text)
+				}
+				return model.Translate(ctx, text, targetLanguages(flags)[0])
+			})
+		} else {
+			reqCtx, cancel := requestContext(ctx, cfg)
+			output, err = runOperation(reqCtx, model, flags, input)
+			cancel()
+		}
+		if err != nil {
+			return "", err
+		}
+		if flags.IsTranslate {
+			for _, v := range terms.Verify(input, output, targetLanguages(flags)[0]) {
+				warnf(ctx, "Glossary violation (%s): %s", item.ID, v)
+			}
+		}
+
+		if flags.OutDir != "" {
+			out, ok := outPaths[item.ID]
+			if !ok {
+				return "", fmt.Errorf("record id %q is not a valid file name", item.ID)
+			}
+			if err := cli.WriteFile(out, []byte(output)); err != nil {
+				return "", err
+			}
+		}
+		return output, nil
+	}
+
+	onResult := func(res batch.Result) {
+		if results != nil {
+			line, _ := json.Marshal(res)
+			if _, err := results.Write(append(line, '\n')); err != nil {
+				log.Printf("Error writing results: %v", err)
+			}
+		}
+		if flags.Output == outputJSONL {
+			report := itemReport{usage: &ai.Usage{}}
+			if r, ok := reports.LoadAndDelete(res.ID); ok {
+				report = r.(itemReport)
+			}
+			e := newEnvelope(res.Output, flags.Provider, operationName(flags), report.usage, report.latency, report.warnings)
+			e.ID, e.Path, e.Status, e.Error = res.ID, res.Path, res.Status, res.Error
+			if err := printEnvelope(e, outputJSONL); err != nil {
+				log.Printf("Error writing output: %v", err)
+			}
+		}
+		infof(ctx, "[%s] %s", res.Status, res.ID)
+	}
+
+	summary := batch.Run(ctx, pending, opts, process, onResult)
+	summary.Skipped += len(items) - len(pending)
+	summary.Total = len(items)
+
+	infof(ctx, "\nBatch finished: %d total, %d succeeded, %d failed, %d skipped",
+		summary.Total, summary.Succeeded, summary.Failed, summary.Skipped)
+	for _, f := range summary.Failures {
+		infof(ctx, "  %s: %s", f.ID, f.Error)
+	}
+
+	if summary.Failed > 0 {
+		return fmt.Errorf("%d of %d items failed, rerun with --resume to retry them", summary.Failed, summary.Total)
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("interrupted, rerun with --resume to continue")
+	}
+	return nil
+}
+
+func loadBatchItems(args []string) ([]batch.Item, error) {
+	var items []batch.Item
+	var patterns []string
+	for _, arg := range args {
+		if strings.HasSuffix(arg, ".jsonl") {
+			records, err := batch.LoadRecords(arg)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, records...)
+			continue
+		}
+		patterns = append(patterns, arg)
+	}
+
+	if len(patterns) > 0 {
+		files, err := cli.ExpandFiles(patterns)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range files {
+			items = append(items, batch.Item{ID: cli.RelPath(name), Path: name})
+		}
+	}
+	return items, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"ai/internal/check"
+	"ai/internal/cli"
+	"ai/internal/config"
+	"ai/internal/provider/ai"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// runCheck asks the model for the issues of every input file (or the prompt/stdin) and
+// returns them as compiler style diagnostics with the number of issues found. Each file is
+// checked with its own request timeout.
+func runCheck(ctx context.Context, model ai.Provider, flags *cli.CMDFlags, cfg *config.Config) (string, int, error) {
+	type source struct {
+		name string
+		text string
+	}
+
+	var sources []source
+	if len(flags.Files) > 0 {
+		files, err := cli.ExpandFiles(flags.Files)
+		if err != nil {
+			return "", 0, err
+		}
+		for _, name := range files {
+			text, err := cli.ReadFile(name, cfg.InputFileLimitKB, cfg.InputDocumentLimitKB, extractHTML(flags))
+			if errors.Is(err, cli.ErrBinary) && !cli.Named(flags.Files, name) {
+				warnf(ctx, "Skipping binary file %s", cli.RelPath(name))
+				continue
+			}
+			if err != nil {
+				return "", 0, fmt.Errorf("%s: %w", name, err)
+			}
+			sources = append(sources, source{cli.RelPath(name), text})
+		}
+		if len(sources) == 0 {
+			return "", 0, fmt.Errorf("no text files in %s", strings.Join(flags.Files, ", "))
+		}
+	} else {
+		name := "<input>"
+		if flags.Input == "" {
+			name = "<stdin>"
+		}
+		text, _, err := readInput(ctx, flags, cfg)
+		if err != nil {
+			return "", 0, err
+		}
+		sources = append(sources, source{name, text})
+	}
+
+	var sb strings.Builder
+	total := 0
+	for _, src := range sources {
+		reqCtx, cancel := requestContext(ctx, cfg)
+		reply, err := model.Check(reqCtx, src.text)
+		cancel()
+		if err != nil {
+			return "", 0, fmt.Errorf("%s: %w", src.name, err)
+		}
+		issues, err := check.Parse(reply)
+		if err != nil {
+			return "", 0, fmt.Errorf("%s: %w", src.name, err)
+		}
+
+		valid, dropped := check.Validate(src.text, issues)
+		if dropped > 0 {
+			warnf(ctx, "%s: ignored %d issue(s) not found in the text", src.name, dropped)
+		}
+		sb.WriteString(check.Format(src.name, src.text, valid))
+		total += len(valid)
+	}
+	return sb.String(), total, nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"ai/internal/cli"
+	"ai/internal/config"
+	"ai/internal/git"
+	"ai/internal/markdown"
+	"ai/internal/provider/ai"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// runCommitMsg asks the model for a commit message describing the staged changes. Positional
+// arguments are pathspecs limiting the diff. With --commit, the message is committed after
+// confirmation, or after review in the git editor with --edit.
+func runCommitMsg(ctx context.Context, model ai.Provider, flags *cli.CMDFlags, cfg *config.Config) (string, error) {
+	diff, err := git.Diff("", true, flags.Args, cfg.CommitMsg.Exclude)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(diff) == "" {
+		return "", errors.New("no staged changes, add them with git add")
+	}
+
+	reply, err := model.General(ctx, commitMsgPrompt(ctx, cfg, flags.Input, diff))
+	if err != nil {
+		return "", err
+	}
+	message := cleanCommitMsg(reply)
+	if message == "" {
+		return "", errors.New("empty commit message in the answer")
+	}
+	if !flags.Commit {
+		return message, nil
+	}
+
+	if !flags.Edit && !flags.Yes {
+		fmt.Fprintf(os.Stderr, "%s\n\n", message)
+		ok, err := cli.Confirm("Commit with this message?")
+		if err != nil {
+			return message, err
+		}
+		if !ok {
+			infof(ctx, "Aborted, nothing committed")
+			return message, nil
+		}
+	}
+	return message, git.Commit(message+"\n", flags.Edit)
+}
+
+// commitMsgPrompt composes the prompt from the template, the author's notes (--input) and the diff.
+// The diff is cut per file to InputFileLimitKB, files that do not fit are only listed.
+func commitMsgPrompt(ctx context.Context, cfg *config.Config, notes, diff string) string {
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(cfg.Prompts.CommitMsg, "\n"))
+	b.WriteString("\n" + strings.TrimRight(cfg.CommitMsg.Template, "\n") + "\n\n")
+	if notes = strings.TrimSpace(notes); notes != "" {
+		b.WriteString("Notes from the author:\n" + notes + "\n\n")
+	}
+
+	limit := cfg.InputFileLimitKB * 1024
+	var included strings.Builder
+	var omitted []string
+	for _, f := range git.Split(diff) {
+		if included.Len()+len(f.Diff) > limit {
+			added, removed := diffStat(f.Diff)
+			omitted = append(omitted, fmt.Sprintf("%s (+%d -%d)", f.Path, added, removed))
+			continue
+		}
+		included.WriteString(f.Diff)
+	}
+	if len(omitted) > 0 {
+		warnf(ctx, "Staged diff over %dKB, only the names of %d file(s) are sent", cfg.InputFileLimitKB, len(omitted))
+	}
+
+	b.WriteString("Staged changes:\n")
+	b.WriteString(included.String())
+	if len(omitted) > 0 {
+		b.WriteString("\nOther changed files, diff left out for size:\n")
+		for _, name := range omitted {
+			b.WriteString("- " + name + "\n")
+		}
+	}
+	return b.String()
+}
+
+// diffStat counts the added and removed lines of a file diff
+func diffStat(diff string) (int, int) {
+	added, removed := 0, 0
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// cleanCommitMsg removes the fence models sometimes wrap the message in
+func cleanCommitMsg(reply string) string {
+	reply = strings.TrimSpace(reply)
+	if blocks := markdown.CodeBlocks(reply); len(blocks) == 1 && strings.HasPrefix(reply, "```") {
+		reply = strings.TrimSpace(blocks[0].Code)
+	}
+	return reply
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"ai/internal/cli"
+	"ai/internal/compare"
+	"ai/internal/config"
+	"ai/internal/glossary"
+	"ai/internal/provider/ai"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const defaultTerminalWidth = 120
+
+// runCompare sends the same operation to every provider listed in --compare concurrently. Each
+// candidate and the judge get their own request timeout.
+func runCompare(ctx context.Context, flags *cli.CMDFlags, cfg *config.Config, terms *glossary.Glossary) (string, error) {
+	var names []string
+	for _, name := range strings.Split(flags.Compare, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("no providers to compare")
+	}
+
+	input, images, err := readInput(ctx, flags, cfg)
+	if err != nil {
+		return "", err
+	}
+	if flags.IsExplain {
+		input = explainInput(ctx, flags, cfg, input)
+	}
+
+	results := make([]compare.Result, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = compareOne(ctx, name, flags, cfg, input, images, terms)
+		}()
+	}
+	wg.Wait()
+
+	if flags.Judge != "" {
+		return runJudge(ctx, flags, cfg, input, results)
+	}
+
+	return compare.Render(results, flags.CompareView, terminalWidth())
+}
+
+// runJudge lets the --judge provider pick or merge the best candidate answer according to the rubric
+func runJudge(ctx context.Context, flags *cli.CMDFlags, cfg *config.Config, input string, results []compare.Result) (string, error) {
+	succeeded := 0
+	for _, r := range results {
+		if r.Error == "" {
+			succeeded++
+		} else {
+			warnf(ctx, "Skipping %s: %s", r.Provider, r.Error)
+		}
+	}
+	if succeeded == 0 {
+		return "", fmt.Errorf("no candidate answers to judge")
+	}
+
+	judge, err := newProvider(flags.Judge, cfg)
+	if err != nil {
+		return "", fmt.Errorf("judge: %w", err)
+	}
+
+	task := "Operation: " + operationName(flags) + "\nInput:\n" + input
+	ctx, cancel := requestContext(ctx, cfg)
+	defer cancel()
+	reply, err := judge.General(ctx, compare.JudgePrompt(cfg.Prompts.Judge, task, results))
+	if err != nil {
+		return "", fmt.Errorf("judge: %w", err)
+	}
+
+	answer, rationale := compare.ParseVerdict(reply)
+	if flags.Rationale && rationale != "" {
+		fmt.Fprintf(os.Stderr, "Judge (%s) rationale:\n%s\n", flags.Judge, rationale)
+	}
+	return answer, nil
+}
+
+func compareOne(ctx context.Context, name string, flags *cli.CMDFlags, cfg *config.Config, input string, images []ai.Image, terms *glossary.Glossary) compare.Result {
+	res := compare.Result{Provider: name}
+
+	model, err := newProvider(name, cfg)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	model.SetImages(images)
+	applyOptions(model, flags, terms)
+
+	ctx, usage := ai.TrackUsage(ctx)
+	ctx, cancel := requestContext(ctx, cfg)
+	defer cancel()
+	output, err := runOperation(ctx, model, flags, input)
+	if err != nil {
+		res.Error = err.Error()
+	}
+	res.Output = output
+	res.Model = usage.Model
+	res.Latency = usage.Latency
+	res.InputTokens = usage.InputTokens
+	res.OutputTokens = usage.OutputTokens
+	return res
+}
+
+func terminalWidth() int {
+	if cols, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && cols > 0 {
+		return cols
+	}
+	return defaultTerminalWidth
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"ai/internal/cli"
+	"ai/internal/config"
+	"ai/internal/explain"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Source files included with --sources, and the lines shown around each referenced line
+const (
+	explainMaxSources = 5
+	explainContext    = 10
+)
+
+// explainInput prepares piped output for --explain: it is cleaned, labeled with the tool that
+// produced it and, with --sources, followed by the project source lines the trace points at
+func explainInput(ctx context.Context, flags *cli.CMDFlags, cfg *config.Config, input string) string {
+	text := explain.Clean(input)
+	var b strings.Builder
+	if tool := explain.Detect(text); tool != "" {
+		b.WriteString("Detected: " + tool + "\n\n")
+	}
+	b.WriteString("Output:\n" + text + "\n")
+	if flags.Sources {
+		b.WriteString(referencedSources(ctx, cfg, text))
+	}
+	return b.String()
+}
+
+// referencedSources returns the snippets of the files referenced by the output that are in
+// the current directory and not ignored, within the input size limits
+func referencedSources(ctx context.Context, cfg *config.Config, text string) string {
+	ignore, err := cli.LoadIgnore(".")
+	if err != nil {
+		warnf(ctx, "Error reading ignore files: %v", err)
+		return ""
+	}
+
+	var paths []string
+	lines := map[string][]int{}
+	for _, ref := range explain.References(text) {
+		path, ok := projectFile(ref.Path)
+		if !ok || ignore.Ignored(path, false) {
+			continue
+		}
+		if _, seen := lines[path]; !seen {
+			if len(paths) == explainMaxSources {
+				continue
+			}
+			paths = append(paths, path)
+		}
+		lines[path] = append(lines[path], ref.Line)
+	}
+
+	var b strings.Builder
+	total := 0
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			warnf(ctx, "Skipped source %s: %v", path, err)
+			continue
+		}
+		if len(data) > cfg.InputFileLimitKB*1024 {
+			warnf(ctx, "Skipped source %s: file too large (limit %dKB)", path, cfg.InputFileLimitKB)
+			continue
+		}
+		snippet := explain.Snippet(string(data), lines[path], explainContext)
+		if total += len(snippet); total > cfg.InputTotalLimitKB*1024 {
+			warnf(ctx, "Skipped source %s: combined input too large (limit %dKB)", path, cfg.InputTotalLimitKB)
+			break
+		}
+		b.WriteString("\n=== " + path + " ===\n" + snippet)
+	}
+	if b.Len() == 0 {
+		infof(ctx, "No referenced source file found in the current directory")
+		return ""
+	}
+	return "\nReferenced source lines (marked with >):\n" + b.String()
+}
+
+// projectFile returns the path relative to the current directory of a file referenced by the
+// output, when it exists under the current directory
+func projectFile(name string) (string, bool) {
+	rel := cli.RelPath(filepath.FromSlash(name))
+	if rel == ".." || strings.HasPrefix(rel, "../") || filepath.IsAbs(rel) {
+		return "", false
+	}
+	info, err := os.Stat(rel)
+	if err != nil || info.IsDir() {
+		return "", false
+	}
+	return rel, true
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"ai/internal/cli"
+	"ai/internal/markdown"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// File extensions for code blocks without a file name hint
+var codeExtensions = map[string]string{
+	"go": "go", "golang": "go", "python": "py", "py": "py", "javascript": "js", "js": "js",
+	"typescript": "ts", "ts": "ts", "jsx": "jsx", "tsx": "tsx", "bash": "sh", "sh": "sh", "shell": "sh",
+	"zsh": "sh", "json": "json", "yaml": "yaml", "yml": "yml", "toml": "toml", "html": "html", "css": "css",
+	"sql": "sql", "rust": "rs", "java": "java", "kotlin": "kt", "c": "c", "cpp": "cpp", "c++": "cpp",
+	"csharp": "cs", "ruby": "rb", "php": "php", "markdown": "md", "md": "md", "xml": "xml", "dockerfile": "Dockerfile",
+}
+
+func codeBlocks(res, lang string) []markdown.CodeBlock {
+	var blocks []markdown.CodeBlock
+	for _, b := range markdown.CodeBlocks(res) {
+		if lang == "" || markdown.SameLang(b.Lang, lang) {
+			blocks = append(blocks, b)
+		}
+	}
+	return blocks
+}
+
+// extractCode returns the code of the fenced blocks of the answer, only those in lang when set.
+// An answer without any fence is kept as is, models sometimes follow "reply only with code".
+func extractCode(ctx context.Context, res, lang string) (string, error) {
+	blocks := codeBlocks(res, lang)
+	if len(blocks) == 0 {
+		if lang != "" {
+			return "", fmt.Errorf("no %s code block in the answer", lang)
+		}
+		warnf(ctx, "No code block in the answer, keeping it as is")
+		return res, nil
+	}
+
+	codes := make([]string, len(blocks))
+	for i, b := range blocks {
+		codes[i] = b.Code
+	}
+	return strings.Join(codes, "\n"), nil
+}
+
+// extractToDir writes every code block of the answer to a file under dir, named after the file
+// name hint of the answer (block-N.ext otherwise). Existing files are only replaced after confirmation.
+func extractToDir(ctx context.Context, flags *cli.CMDFlags, res string) error {
+	blocks := codeBlocks(res, flags.ExtractLang)
+	if len(blocks) == 0 {
+		return fmt.Errorf("no code blocks in the answer")
+	}
+
+	// Check every path before writing anything
+	targets := make([]string, len(blocks))
+	for i, b := range blocks {
+		name := b.Filename
+		if name == "" {
+			ext, ok := codeExtensions[strings.ToLower(b.Lang)]
+			if !ok {
+				ext = "txt"
+			}
+			name = fmt.Sprintf("block-%d.%s", i+1, ext)
+		}
+		target, err := extractPath(flags.ExtractTo, name)
+		if err != nil {
+			return err
+		}
+		targets[i] = target
+	}
+
+	written := map[string]bool{}
+	for i, b := range blocks {
+		target := targets[i]
+		if written[target] {
+			warnf(ctx, "%s appears more than once in the answer, keeping the last block", target)
+		} else if _, err := os.Stat(target); err == nil && !flags.Yes {
+			ok, err := cli.Confirm("Overwrite " + target + "?")
+			if err != nil {
+				return err
+			}
+			if !ok {
+				infof(ctx, "Skipped %s", target)
+				continue
+			}
+		}
+
+		if err := cli.WriteFile(target, []byte(b.Code)); err != nil {
+			return err
+		}
+		written[target] = true
+		infof(ctx, "Wrote %s", target)
+	}
+	return nil
+}
+
+// extractPath joins dir and a file name from the answer, refusing names that leave dir
+func extractPath(dir, name string) (string, error) {
+	name = filepath.FromSlash(name)
+	if filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("refusing to write %s outside of %s", name, dir)
+	}
+	target := filepath.Join(dir, name)
+	if rel, err := filepath.Rel(dir, target); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("refusing to write %s outside of %s", name, dir)
+	}
+	return target, nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"ai/internal/cli"
+	"ai/internal/config"
+	"ai/internal/locale"
+	"ai/internal/provider/ai"
+	"ai/internal/segment"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+const localeInstructions = "Translate only the text. Keep placeholders such as {name}, {{count}}, %s, %1$d and %@ exactly as they are," +
+	" keep HTML tags and the [[n]] marker lines, and do not add explanations."
+
+// runTranslateLocale translates the values of a localization file into every -l language,
+// keeping keys and placeholders: ai translate-locale en.json -l de,fr
+func runTranslateLocale(flags *cli.CMDFlags, cfg *config.Config) error {
+	if len(flags.Args) != 1 {
+		return fmt.Errorf("usage: ai translate-locale [flags] <file> -l <languages>")
+	}
+	if flags.Language == "" {
+		return fmt.Errorf("missing target language, e.g. -l de")
+	}
+	langs := targetLanguages(flags)
+	if flags.ToFile != "" && len(langs) > 1 {
+		return fmt.Errorf("--tofile can only be used with a single target language")
+	}
+
+	path := flags.Args[0]
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading file: %w", err)
+	}
+	doc, err := locale.Parse(path, data)
+	if err != nil {
+		return err
+	}
+
+	from := flags.From
+	if from == "" {
+		from = locale.SourceLanguage(path)
+	}
+
+	flags.IsTranslate = true // the glossary only applies to translations
+	terms, err := loadGlossary(context.Background(), flags, cfg)
+	if err != nil {
+		return err
+	}
+	model, err := newProvider(flags.Provider, cfg)
+	if err != nil {
+		return err
+	}
+	model.SetTranslateOptions(ai.TranslateOptions{
+		From:         from,
+		Glossary:     terms,
+		Instructions: localeInstructions,
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	for _, lang := range langs {
+		target := flags.ToFile
+		if target == "" {
+			target = locale.TargetPath(path, from, lang)
+		}
+		if err := translateLocale(ctx, model, cfg, doc, from, lang, target); err != nil {
+			return fmt.Errorf("%s: %w", lang, err)
+		}
+	}
+	return nil
+}
+
+// translateLocale writes the translation of doc to target. Entries already translated in an
+// existing target file are kept, strings that fail placeholder verification keep the source text.
+func translateLocale(ctx context.Context, model ai.Provider, cfg *config.Config, doc locale.Document, from, lang, target string) error {
+	translations := map[string]string{}
+	if data, err := os.ReadFile(target); err == nil {
+		existing, err := locale.Parse(target, data)
+		if err != nil {
+			return fmt.Errorf("existing %s: %w", target, err)
+		}
+		for _, e := range existing.Entries() {
+			translations[e.Key] = e.Source
+		}
+	}
+
+	var pending []locale.Entry
+	for _, e := range doc.Entries() {
+		if t, ok := translations[e.Key]; !ok || t == "" || t == e.Source {
+			delete(translations, e.Key)
+			pending = append(pending, e)
+		}
+	}
+	kept := len(doc.Entries()) - len(pending)
+
+	failed := 0
+	batchSize := max(cfg.Locale.BatchSize, 1)
+	for start := 0; start < len(pending); start += batchSize {
+		batch := pending[start:min(start+batchSize, len(pending))]
+		fmt.Fprintf(os.Stderr, "Translating %s strings %d-%d of %d\n", lang, start+1, start+len(batch), len(pending))
+
+		texts := make([]string, len(batch))
+		for i, e := range batch {
+			texts[i] = e.Source
+		}
+		translated, errs, err := translateStrings(ctx, model, cfg, texts, lang)
+		if err != nil {
+			return err
+		}
+		for i, e := range batch {
+			if errs[i] != nil {
+				failed++
+				fmt.Fprintf(os.Stderr, "Not translated %s: %v\n", e.Key, errs[i])
+				continue
+			}
+			translations[e.Key] = translated[i]
+		}
+	}
+
+	out, err := doc.Render(translations, from, lang)
+	if err != nil {
+		return err
+	}
+	if err := cli.WriteFile(target, out); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s (%d translated, %d already translated, %d failed)\n", target, len(pending)-failed, kept, failed)
+	return nil
+}
+
+// translateStrings translates a batch in one request and verifies the placeholders of every
+// string. Strings missing from the reply or with changed placeholders are retried one by one,
+// those still failing get an error in the returned slice.
+func translateStrings(ctx context.Context, model ai.Provider, cfg *config.Config, texts []string, lang string) ([]string, []error, error) {
+	translated := make([]string, len(texts))
+	errs := make([]error, len(texts))
+
+	reply, err := translateRequest(ctx, model, cfg, segment.Batch(texts), lang)
+	if err != nil {
+		return nil, nil, err
+	}
+	parts, err := segment.Unbatch(reply, len(texts))
+	if err == nil {
+		copy(translated, parts)
+	} else {
+		fmt.Fprintf(os.Stderr, "Batch reply was not usable (%v), translating string by string\n", err)
+	}
+
+	for i, text := range texts {
+		if err == nil && locale.CheckPlaceholders(text, translated[i]) == nil {
+			continue
+		}
+		reply, reqErr := translateRequest(ctx, model, cfg, text, lang)
+		if reqErr != nil {
+			return nil, nil, reqErr
+		}
+		translated[i] = strings.TrimSpace(reply)
+		errs[i] = locale.CheckPlaceholders(text, translated[i])
+	}
+	return translated, errs, nil
+}
+
+func translateRequest(ctx context.Context, model ai.Provider, cfg *config.Config, text, lang string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(cfg.HttpTimeoutSeconds)*time.Second)
+	defer cancel()
+	return model.Translate(ctx, text, lang)
+}
@@ -3,7 +3,10 @@ package main
 import (
 	"ai/internal/cli"
 	"ai/internal/config"
+	"ai/internal/glossary"
+	"ai/internal/markdown"
 	"ai/internal/provider/ai"
+	"ai/internal/review"
 	"context"
 	"fmt"
 	"github.com/atotto/clipboard"
@@ -16,6 +19,7 @@ import (
 )
 
 const defaultTargetLanguage = "English"
+const defaultImagePrompt = "Describe the attached image."
 
 func readStdin(sizeLimitKB int) (string, error) {
 	info, err := os.Stdin.Stat()
@@ -37,18 +41,67 @@ func readStdin(sizeLimitKB int) (string, error) {
 	return string(data), nil
 }
 
-func runModel(model ai.Provider, ctx context.Context, flags *cli.CMDFlags, cfg *config.Config) (string, error) {
+// applyOptions passes the operation options selected by the flags to the provider
+func applyOptions(model ai.Provider, flags *cli.CMDFlags, terms *glossary.Glossary) {
+	var instructions string
+	if usesMarkdown(flags) {
+		instructions = markdownInstructions
+	}
+	model.SetRewriteOptions(ai.RewriteOptions{
+		Tone:         flags.Tone,
+		Style:        flags.Style,
+		Length:       flags.Length,
+		Instructions: instructions,
+	})
+	model.SetSummaryOptions(ai.SummaryOptions{
+		Format:    flags.Format,
+		Words:     flags.Words,
+		Sentences: flags.Sentences,
+	})
+	model.SetTranslateOptions(ai.TranslateOptions{
+		From:         flags.From,
+		Glossary:     terms,
+		Instructions: instructions,
+	})
+}
+
+func newProvider(name string, cfg *config.Config) (ai.Provider, error) {
+	models := map[string]func() (ai.Provider, error){
+		"gemini": func() (ai.Provider, error) { return ai.NewGemini(os.Getenv("GEMINI_API_KEY"), cfg.Models.Gemini, cfg) },
+		"openai": func() (ai.Provider, error) { return ai.NewOpenai(os.Getenv("OPENAI_API_KEY"), cfg.Models.Openai, cfg) },
+		"claude": func() (ai.Provider, error) { return ai.NewClaude(os.Getenv("CLAUDE_API_KEY"), cfg.Models.Claude, cfg) },
+		"ollama": func() (ai.Provider, error) { return ai.NewOllama(cfg.Models.Ollama, cfg) },
+		"":       func() (ai.Provider, error) { return ai.NewOllama(cfg.Models.Ollama, cfg) },
+	}
+
+	newModel, ok := models[name]
+	if !ok {
+		return nil, fmt.Errorf("model not implemented: %s", name)
+	}
+	return newModel()
+}
+
+// extractHTML reports whether HTML files are sent as their text instead of the markup: with
+// --html-text, and for summaries which only need the text
+func extractHTML(flags *cli.CMDFlags) bool {
+	return flags.HTMLText || flags.IsSummarize
+}
 
+// readInput gathers the prompt from --input, --file and stdin, and loads the attached images
+func readInput(ctx context.Context, flags *cli.CMDFlags, cfg *config.Config) (string, []ai.Image, error) {
 	var inputParts []string
 
 	if flags.Input != "" {
 		inputParts = append(inputParts, flags.Input)
 	}
 
-	if flags.File != "" {
-		fileContent, err := cli.ReadFile(flags.File, cfg.InputFileLimitKB)
+	if len(flags.Files) > 0 {
+		fileContent, skipped, err := cli.ReadFiles(flags.Files, cfg.InputFileLimitKB, cfg.InputDocumentLimitKB, cfg.InputTotalLimitKB, extractHTML(flags))
+		for _, name := range skipped {
+			warnf(ctx, "Skipping binary file %s", cli.RelPath(name))
+		}
 		if err != nil {
-			return "", err
+			return "", nil, err
 		}
 		inputParts = append(inputParts, fileContent)
 	}
@@ -56,30 +109,89 @@ func runModel(model ai.Provider, ctx context.Context, flags *cli.CMDFlags, cfg *
 	if len(inputParts) == 0 {
 		stdinContent, err := readStdin(cfg.InputFileLimitKB)
 		if err != nil {
-			return "", err
+			return "", nil, err
 		}
 		if stdinContent != "" {
 			inputParts = append(inputParts, stdinContent)
 		}
 	}
 
+	var images []ai.Image
+	for _, path := range flags.Images {
+		mimeType, data, err := cli.ReadImage(path, cfg.InputImageLimitKB)
+		if err != nil {
+			return "", nil, err
+		}
+		images = append(images, ai.Image{MimeType: mimeType, Data: data})
+	}
+
 	input := strings.Join(inputParts, "\n")
+	if input == "" && len(images) > 0 {
+		input = defaultImagePrompt
+	}
 	if input == "" {
-		return "", fmt.Errorf("missing input")
+		return "", nil, fmt.Errorf("missing input")
+	}
+
+	return input, images, nil
+}
+
+// formatResult renders Markdown answers for the terminal. The text is printed as is with --raw
+// or when stdout is not a terminal, so pipes and redirects get plain output.
+func formatResult(res string, raw bool) string {
+	if raw || !cli.IsTerminal(os.Stdout) {
+		return res
+	}
+	return "\n" + markdown.Terminal(res, terminalWidth(), cli.UseColor()) + "\n"
+}
+
+// operationName describes the operation selected by the flags
+func operationName(flags *cli.CMDFlags) string {
+	switch {
+	case flags.Command == "commit-msg", flags.Command == "review":
+		return flags.Command
+	case flags.IsRewrite:
+		return "rewrite"
+	case flags.IsTranslate:
+		return "translate to " + strings.Join(targetLanguages(flags), ", ")
+	case flags.IsSummarize:
+		return "summarize"
+	case flags.IsCheck:
+		return "check"
+	case flags.IsPatch:
+		return "patch"
+	case flags.IsExplain:
+		return "explain"
+	case flags.IsAgent:
+		return "agent"
+	default:
+		return "general"
 	}
+}
 
+// requestContext limits a single request to the HTTP timeout of the config
+func requestContext(ctx context.Context, cfg *config.Config) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(cfg.HttpTimeoutSeconds)*time.Second)
+}
+
+// runOperation dispatches the input to the operation selected by the flags
+func runOperation(ctx context.Context, model ai.Provider, flags *cli.CMDFlags, input string) (string, error) {
 	// Handle conditional flags
 	switch {
 	case flags.IsRewrite:
 		return model.Rewrite(ctx, input)
 	case flags.IsTranslate:
-		lang := flags.Language
-		if lang == "" {
-			lang = defaultTargetLanguage
+		langs := targetLanguages(flags)
+		if len(langs) > 1 {
+			return "", fmt.Errorf("multiple target languages are not supported in this mode")
 		}
-		return model.Translate(ctx, input, lang)
+		return model.Translate(ctx, input, langs[0])
 	case flags.IsSummarize:
 		return model.Summarize(ctx, input)
+	case flags.IsCheck:
+		return model.Check(ctx, input)
+	case flags.IsExplain:
+		return model.Explain(ctx, input)
 	default:
 		return model.General(ctx, input)
 	}
@@ -98,51 +210,278 @@ func main() {
 	// Set CMD flags
 	cmdFlags := cli.SetFlags()
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.HttpTimeoutSeconds)*time.Second)
+	// Scripts get errors as JSON on stderr
+	if envelopeOutput(cmdFlags.Output) {
+		log.SetFlags(0)
+		log.SetOutput(jsonErrors{})
+	}
+	if err := checkOutputFlag(cmdFlags); err != nil {
+		log.Fatal(err)
+	}
+
+	if cmdFlags.Command == "batch" {
+		if err := runBatch(cmdFlags, cfg); err != nil {
+			log.Fatalf("Error running batch: %v", err)
+		}
+		return
+	}
+	if cmdFlags.Command == "sh" {
+		exitCode, err := runShell(cmdFlags, cfg)
+		if err != nil {
+			log.Fatalf("Error running shell command: %v", err)
+		}
+		os.Exit(exitCode)
+	}
+	if cmdFlags.Command == "translate-locale" {
+		if err := runTranslateLocale(cmdFlags, cfg); err != nil {
+			log.Fatalf("Error translating locale file: %v", err)
+		}
+		return
+	}
+
+	// Usage, latency and warnings are reported in the JSON envelope
+	start := time.Now()
+	base, usage := ai.TrackUsage(context.Background())
+	var warnings *warningLog
+	if envelopeOutput(cmdFlags.Output) {
+		base, warnings = collectWarnings(base)
+	}
+
+	// Single requests run under ctx, commands sending several requests time each one from base
+	ctx, cancel := requestContext(base, cfg)
 	defer cancel()
+	var sourceLanguage string // detected or --from, for translations
+	printJSON := func(text string, translations []translation) {
+		provider := cmdFlags.Provider
+		if cmdFlags.Judge != "" {
+			provider = cmdFlags.Judge
+		}
+		e := newEnvelope(text, provider, operationName(cmdFlags), usage, time.Since(start), warnings)
+		e.SourceLanguage = sourceLanguage
+		if len(translations) > 1 {
+			e.Translations = translations
+		}
+		if err := printEnvelope(e, cmdFlags.Output); err != nil {
+			log.Fatalf("Error writing output: %v", err)
+		}
+	}
 
-	models := map[string]func() (ai.Provider, error){
-		"gemini": func() (ai.Provider, error) { return ai.NewGemini(os.Getenv("GEMINI_API_KEY"), cfg.Models.Gemini, cfg) },
-		"openai": func() (ai.Provider, error) { return ai.NewOpenai(os.Getenv("OPENAI_API_KEY"), cfg.Models.Openai, cfg) },
-		"claude": func() (ai.Provider, error) { return ai.NewClaude(os.Getenv("CLAUDE_API_KEY"), cfg.Models.Claude, cfg) },
-		"ollama": func() (ai.Provider, error) { return ai.NewOllama(cfg.Models.Ollama, cfg) },
-		"":       func() (ai.Provider, error) { return ai.NewOllama(cfg.Models.Ollama, cfg) },
+	if cmdFlags.Command == "commit-msg" {
+		model, err := newProvider(cmdFlags.Provider, cfg)
+		if err != nil {
+			log.Fatalf("Error creating model: %v", err)
+		}
+		message, err := runCommitMsg(ctx, model, cmdFlags, cfg)
+		if err != nil {
+			log.Fatalf("Error writing commit message: %v", err)
+		}
+		switch {
+		case cmdFlags.Output != outputText:
+			printJSON(message, nil)
+		case !cmdFlags.Commit:
+			fmt.Println(message)
+		}
+		return
 	}
 
-	newModel, ok := models[cmdFlags.Provider]
-	if !ok {
-		log.Fatal("Model not implemented")
+	if cmdFlags.Command == "review" {
+		model, err := newProvider(cmdFlags.Provider, cfg)
+		if err != nil {
+			log.Fatalf("Error creating model: %v", err)
+		}
+		findings, err := runReview(base, model, cmdFlags, cfg)
+		if err != nil {
+			log.Fatalf("Error reviewing changes: %v", err)
+		}
+		diagnostics := review.Format(findings)
+		if envelopeOutput(cmdFlags.Output) {
+			e := newEnvelope(diagnostics, cmdFlags.Provider, operationName(cmdFlags), usage, time.Since(start), warnings)
+			e.Findings = findings
+			if err := printEnvelope(e, cmdFlags.Output); err != nil {
+				log.Fatalf("Error writing output: %v", err)
+			}
+		} else {
+			if cmdFlags.Output == outputSARIF {
+				data, err := review.SARIF(findings)
+				if err != nil {
+					log.Fatalf("Error writing output: %v", err)
+				}
+				diagnostics = string(data) + "\n"
+			}
+			if cmdFlags.ToFile != "" {
+				if err := cli.WriteFile(cmdFlags.ToFile, []byte(diagnostics)); err != nil {
+					log.Fatalf("Error writing file: %v", err)
+				}
+			} else {
+				fmt.Print(diagnostics)
+			}
+		}
+		// Fail CI jobs on error findings, warnings and notes are only reported
+		if hasErrors(findings) {
+			os.Exit(1)
+		}
+		return
 	}
 
-	model, err := newModel()
+	if cmdFlags.Judge != "" && cmdFlags.Compare == "" {
+		log.Fatal("--judge requires --compare")
+	}
+	if err := checkOperationFlags(cmdFlags, cfg); err != nil {
+		log.Fatal(err)
+	}
+	terms, err := loadGlossary(base, cmdFlags, cfg)
 	if err != nil {
-		log.Fatalf("Error creating model: %v", err)
+		log.Fatal(err)
 	}
 
-	res, err := runModel(model, ctx, cmdFlags, cfg)
-	if err != nil {
-		log.Fatalf("Error running model: %v", err)
+	if cmdFlags.IsPatch {
+		model, err := newProvider(cmdFlags.Provider, cfg)
+		if err != nil {
+			log.Fatalf("Error creating model: %v", err)
+		}
+		diffText, rejected, err := runPatch(ctx, model, cmdFlags, cfg)
+		if err != nil {
+			log.Fatalf("Error patching files: %v", err)
+		}
+		if cmdFlags.Output != outputText {
+			printJSON(diffText, nil)
+		}
+		// Like patch(1), exit with 1 when hunks were rejected
+		if rejected > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if cmdFlags.IsCheck && cmdFlags.Compare == "" {
+		model, err := newProvider(cmdFlags.Provider, cfg)
+		if err != nil {
+			log.Fatalf("Error creating model: %v", err)
+		}
+		diagnostics, issues, err := runCheck(base, model, cmdFlags, cfg)
+		if err != nil {
+			log.Fatalf("Error checking text: %v", err)
+		}
+		if cmdFlags.ToFile != "" {
+			if err := cli.WriteFile(cmdFlags.ToFile, []byte(diagnostics)); err != nil {
+				log.Fatalf("Error writing file: %v", err)
+			}
+		}
+		if cmdFlags.Output != outputText {
+			printJSON(diagnostics, nil)
+		} else if cmdFlags.ToFile == "" {
+			fmt.Print(diagnostics)
+		}
+		// Like linters, exit with 1 when issues were found
+		if issues > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	var res, input string
+	var translations []translation
+	if cmdFlags.Compare != "" {
+		res, err = runCompare(base, cmdFlags, cfg, terms)
+		if err != nil {
+			log.Fatalf("Error comparing models: %v", err)
+		}
+	} else {
+		model, err := newProvider(cmdFlags.Provider, cfg)
+		if err != nil {
+			log.Fatalf("Error creating model: %v", err)
+		}
+
+		var images []ai.Image
+		input, images, err = readInput(base, cmdFlags, cfg)
+		if err != nil {
+			log.Fatalf("Error running model: %v", err)
+		}
+		if cmdFlags.IsExplain {
+			input = explainInput(ctx, cmdFlags, cfg, input)
+		}
+		model.SetImages(images)
+		applyOptions(model, cmdFlags, terms)
+
+		if cmdFlags.IsTranslate {
+			translations, sourceLanguage, err = runTranslate(base, model, cmdFlags, cfg, input, terms)
+			res = joinTranslations(translations)
+		} else if cmdFlags.IsAgent {
+			res, err = runAgent(ctx, model, cmdFlags, cfg, input)
+		} else if cmdFlags.IsRewrite && markdownInput(cmdFlags) {
+			res, err = processMarkdown(base, input, cfg, model.Rewrite)
+		} else {
+			res, err = runOperation(ctx, model, cmdFlags, input)
+		}
+		if err != nil {
+			log.Fatalf("Error running model: %v", err)
+		}
+	}
+
+	// Keep only the code blocks of the answer
+	if cmdFlags.ExtractTo != "" {
+		if err := extractToDir(ctx, cmdFlags, res); err != nil {
+			log.Fatalf("Error extracting code: %v", err)
+		}
+		if cmdFlags.Output != outputText {
+			printJSON(res, translations)
+		}
+		return
+	}
+	if cmdFlags.ExtractCode {
+		if res, err = extractCode(ctx, res, cmdFlags.ExtractLang); err != nil {
+			log.Fatalf("Error extracting code: %v", err)
+		}
+	}
+
+	// Write the rewrite back into the source file
+	if cmdFlags.InPlace {
+		// The diff goes to stderr when stdout carries the JSON output
+		if cmdFlags.Diff && cmdFlags.Output != outputText {
+			fmt.Fprintln(os.Stderr, renderDiff(input, res, cmdFlags.DiffMode))
+		} else if cmdFlags.Diff {
+			fmt.Println(renderDiff(input, res, cmdFlags.DiffMode))
+		}
+		if err := writeInPlace(cmdFlags, input, res); err != nil {
+			log.Fatalf("Error writing file: %v", err)
+		}
+		if cmdFlags.Output != outputText {
+			printJSON(res, translations)
+		}
+		return
 	}
 
 	// Copy to clipboard
 	if cmdFlags.IsClipboard {
 		err = clipboard.WriteAll(res)
 		if err != nil {
-			fmt.Println("Error copying to clipboard:", err)
+			warnf(ctx, "Error copying to clipboard: %v", err)
 		}
 	}
 
-	// Output to a file or standard output (stdout)
-	if cmdFlags.ToFile != "" {
+	// Output to a file
+	if cmdFlags.ToFile != "" && len(translations) > 1 {
+		// One file per target language
+		if err := writeTranslations(cmdFlags.ToFile, translations); err != nil {
+			log.Fatalf("Error writing file: %v", err)
+		}
+	} else if cmdFlags.ToFile != "" {
 		err = cli.WriteFile(cmdFlags.ToFile, []byte(res))
 		if err != nil {
 			log.Fatalf("Error writing file: %v", err)
 		}
-	} else {
-		const cyberCyan = "\033[96m" // bright cyan
-		const reset = "\033[0m"
-
-		fmt.Println("\n" + cyberCyan + res + reset + "\n")
 	}
 
+	// Standard output (stdout)
+	switch {
+	case cmdFlags.Output != outputText:
+		printJSON(res, translations)
+	case cmdFlags.ToFile != "":
+	case cmdFlags.Diff:
+		fmt.Println(renderDiff(input, res, cmdFlags.DiffMode))
+	default:
+		// Extracted code, side by side and JSON comparisons are printed as is
+		raw := cmdFlags.Raw || cmdFlags.ExtractCode || cmdFlags.Compare != "" && cmdFlags.CompareView != "sections"
+		fmt.Println(formatResult(res, raw))
+	}
 }
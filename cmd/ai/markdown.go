@@ -0,0 +1,129 @@
+package main
+
+import (
+	"ai/internal/batch"
+	"ai/internal/cli"
+	"ai/internal/config"
+	"ai/internal/markdown"
+	"ai/internal/segment"
+	"context"
+	"fmt"
+	"strings"
+)
+
+const markdownInstructions = "The text is Markdown: keep the Markdown syntax, the [[n]] marker lines" +
+	" and every [[cN]] placeholder exactly as they are."
+
+// markdownInput reports whether the rewrite or translation input is a single Markdown document
+func markdownInput(flags *cli.CMDFlags) bool {
+	if flags.Markdown {
+		return true
+	}
+	return flags.Input == "" && len(flags.Files) == 1 &&
+		!strings.ContainsAny(flags.Files[0], "*?[") && markdown.IsMarkdown(flags.Files[0])
+}
+
+// markdownItem reports whether a batch item is rewritten or translated in Markdown mode
+func markdownItem(flags *cli.CMDFlags, item batch.Item) bool {
+	if !flags.IsRewrite && !flags.IsTranslate || flags.Input != "" {
+		return false
+	}
+	return flags.Markdown || markdown.IsMarkdown(item.Path)
+}
+
+// usesMarkdown reports whether any input may go through Markdown mode
+func usesMarkdown(flags *cli.CMDFlags) bool {
+	if flags.Markdown {
+		return true
+	}
+	for _, path := range append(flags.Files, flags.Args...) {
+		if markdown.IsMarkdown(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// processMarkdown sends only the prose of a Markdown document through send, in batches, and
+// reassembles the document so code, URLs and front matter are kept byte for byte. Each request
+// has its own timeout.
+func processMarkdown(ctx context.Context, input string, cfg *config.Config, send func(context.Context, string) (string, error)) (string, error) {
+	send = timed(cfg, send)
+	doc := markdown.Parse(input)
+	prose := doc.Prose()
+	out := make([]string, len(prose))
+	batchSize := max(cfg.Markdown.BatchSegments, 1)
+
+	for start := 0; start < len(prose); start += batchSize {
+		end := min(start+batchSize, len(prose))
+		processed, err := sendSegments(ctx, prose[start:end], send, func(i int, text string) error {
+			return doc.Verify(start+i, text)
+		})
+		if err != nil {
+			return "", fmt.Errorf("segments %d-%d: %w", start+1, end, err)
+		}
+		copy(out[start:], processed)
+	}
+	return doc.Render(out), nil
+}
+
+// timed gives every call of send its own request timeout
+func timed(cfg *config.Config, send func(context.Context, string) (string, error)) func(context.Context, string) (string, error) {
+	return func(ctx context.Context, text string) (string, error) {
+		ctx, cancel := requestContext(ctx, cfg)
+		defer cancel()
+		return send(ctx, text)
+	}
+}
+
+// sendSegments processes texts in one request, separated by [[n]] markers. When the reply does
+// not keep the markers the batch is retried once, then the texts are sent one at a time. Texts
+// failing verify are retried on their own, those still failing are returned empty.
+func sendSegments(ctx context.Context, texts []string, send func(context.Context, string) (string, error), verify func(i int, text string) error) ([]string, error) {
+	var out []string
+	for attempt := 1; attempt <= 2 && out == nil; attempt++ {
+		reply, err := send(ctx, segment.Batch(texts))
+		if err != nil {
+			return nil, err
+		}
+		if out, err = segment.Unbatch(reply, len(texts)); err != nil {
+			if attempt == 1 {
+				warnf(ctx, "Batch reply was malformed (%v), retrying", err)
+			} else {
+				warnf(ctx, "Batch reply was malformed (%v), sending one segment at a time", err)
+			}
+		}
+	}
+	if out == nil {
+		out = make([]string, len(texts))
+	}
+
+	for i, text := range texts {
+		if strings.TrimSpace(text) == "" {
+			out[i] = text
+			continue
+		}
+		if out[i] != "" && (verify == nil || verify(i, out[i]) == nil) {
+			continue
+		}
+		reply, err := send(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = strings.TrimSpace(reply)
+		if verify != nil {
+			if err := verify(i, out[i]); err != nil {
+				warnf(ctx, "Kept the original of %q: %v", truncate(text, 40), err)
+				out[i] = ""
+			}
+		}
+	}
+	return out, nil
+}
+
+func truncate(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n]) + "..."
+	}
+	return s
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"ai/internal/cli"
+	"ai/internal/provider/ai"
+	"ai/internal/review"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Output formats selected with --output
+const (
+	outputText  = "text"
+	outputJSON  = "json"
+	outputJSONL = "jsonl"
+	outputSARIF = "sarif"
+)
+
+// checkOutputFlag validates --output for the selected mode
+func checkOutputFlag(flags *cli.CMDFlags) error {
+	switch flags.Output {
+	case outputText, outputJSON, outputJSONL, outputSARIF:
+	default:
+		return fmt.Errorf("unknown output format %q (text, json, jsonl, sarif)", flags.Output)
+	}
+	if flags.Output == outputSARIF && flags.Command != "review" {
+		return fmt.Errorf("--output sarif is only supported by ai review")
+	}
+	if flags.Output != outputText && flags.Command == "translate-locale" {
+		return fmt.Errorf("translate-locale writes files, --output is not supported")
+	}
+	if flags.Output != outputText && flags.Command == "sh" {
+		return fmt.Errorf("sh runs commands interactively, --output is not supported")
+	}
+	if flags.Output == outputJSON && flags.Command == "batch" {
+		return fmt.Errorf("batch mode writes one result per line, use --output jsonl")
+	}
+	if flags.Output != outputText && flags.Compare != "" && flags.Judge == "" {
+		return fmt.Errorf("--output %s needs --judge to compare, use --compare-view json for all answers", flags.Output)
+	}
+	return nil
+}
+
+// envelope is the machine-readable result printed with --output json, one per item in batch mode
+type envelope struct {
+	ID             string           `json:"id,omitempty"`
+	Path           string           `json:"path,omitempty"`
+	Status         string           `json:"status,omitempty"`
+	Error          string           `json:"error,omitempty"`
+	Text           string           `json:"text"`
+	Translations   []translation    `json:"translations,omitempty"`
+	Findings       []review.Finding `json:"findings,omitempty"`
+	SourceLanguage string           `json:"sourceLanguage,omitempty"`
+	Provider       string           `json:"provider"`
+	Model          string           `json:"model"`
+	Operation      string           `json:"operation"`
+	Usage          usage            `json:"usage"`
+	LatencyMs      int64            `json:"latencyMs"`
+	FinishReason   string           `json:"finishReason,omitempty"`
+	Warnings       []string         `json:"warnings"`
+}
+
+type usage struct {
+	InputTokens  int `json:"inputTokens"`
+	OutputTokens int `json:"outputTokens"`
+	Requests     int `json:"requests"`
+}
+
+func newEnvelope(text, provider, operation string, u *ai.Usage, latency time.Duration, warnings *warningLog) envelope {
+	if u.Provider != "" {
+		provider = u.Provider
+	}
+	return envelope{
+		Text:         text,
+		Provider:     provider,
+		Model:        u.Model,
+		Operation:    operation,
+		Usage:        usage{InputTokens: u.InputTokens, OutputTokens: u.OutputTokens, Requests: u.Requests},
+		LatencyMs:    latency.Milliseconds(),
+		FinishReason: u.FinishReason,
+		Warnings:     warnings.List(),
+	}
+}
+
+// envelopeOutput reports whether results are printed as JSON envelopes
+func envelopeOutput(format string) bool {
+	return format == outputJSON || format == outputJSONL
+}
+
+// printEnvelope writes the envelope to stdout, indented for json and on a single line for jsonl
+func printEnvelope(e envelope, format string) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	if format == outputJSON {
+		enc.SetIndent("", "  ")
+	}
+	return enc.Encode(e)
+}
+
+// jsonErrors turns log output (log.Fatalf) into {"error": "..."} lines on stderr
+type jsonErrors struct{}
+
+func (jsonErrors) Write(p []byte) (int, error) {
+	enc := json.NewEncoder(os.Stderr)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(map[string]string{"error": strings.TrimSpace(string(p))}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// warningLog collects the warnings of an operation for the JSON envelope
+type warningLog struct {
+	mu   sync.Mutex
+	list []string
+}
+
+type warningsKey struct{}
+
+// collectWarnings returns a context whose warnings and progress notes are kept out of stderr,
+// warnings are added to the returned log instead
+func collectWarnings(ctx context.Context) (context.Context, *warningLog) {
+	w := &warningLog{}
+	return context.WithValue(ctx, warningsKey{}, w), w
+}
+
+// List returns the collected warnings, never nil so the envelope always has an array
+func (w *warningLog) List() []string {
+	if w == nil {
+		return []string{}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string{}, w.list...)
+}
+
+// warnf reports a warning on stderr, or adds it to the log of the context in JSON mode
+func warnf(ctx context.Context, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if w, ok := ctx.Value(warningsKey{}).(*warningLog); ok {
+		w.mu.Lock()
+		w.list = append(w.list, msg)
+		w.mu.Unlock()
+		return
+	}
+	fmt.Fprintln(os.Stderr, msg)
+}
+
+// infof prints a progress note on stderr, notes are dropped in JSON mode
+func infof(ctx context.Context, format string, args ...any) {
+	if _, ok := ctx.Value(warningsKey{}).(*warningLog); ok {
+		return
+	}
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+}
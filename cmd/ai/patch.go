@@ -0,0 +1,177 @@
+package main
+
+import (
+	"ai/internal/cli"
+	"ai/internal/config"
+	"ai/internal/patch"
+	"ai/internal/provider/ai"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const defaultPatchInstructions = "Fix the bugs in the files."
+
+// fileChange is a patched file waiting for confirmation
+type fileChange struct {
+	path   string
+	before string
+	after  string
+	create bool
+	delete bool
+}
+
+// runPatch asks the model for a unified diff of the --file inputs, previews the changes and
+// applies them after confirmation. Hunks that neither apply nor merge are reported and skipped.
+// It returns the diff and the number of rejected hunks.
+func runPatch(ctx context.Context, model ai.Provider, flags *cli.CMDFlags, cfg *config.Config) (string, int, error) {
+	if len(flags.Files) == 0 {
+		return "", 0, fmt.Errorf("--patch needs the files to change as --file inputs")
+	}
+	paths, err := cli.ExpandFiles(flags.Files)
+	if err != nil {
+		return "", 0, err
+	}
+
+	inputs := map[string]string{} // relative path -> path on disk
+	var sources []patch.Source
+	total := 0
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", 0, err
+		}
+		if len(data) > cfg.InputFileLimitKB*1024 {
+			return "", 0, fmt.Errorf("%s: file too large (limit %dKB)", path, cfg.InputFileLimitKB)
+		}
+		if total += len(data); total > cfg.InputTotalLimitKB*1024 {
+			return "", 0, fmt.Errorf("combined input too large (limit %dKB)", cfg.InputTotalLimitKB)
+		}
+		rel := filepath.ToSlash(cli.RelPath(path))
+		inputs[rel] = path
+		sources = append(sources, patch.Source{Path: rel, Content: string(data)})
+	}
+
+	instructions := flags.Input
+	if instructions == "" {
+		if instructions, err = readStdin(cfg.InputFileLimitKB); err != nil {
+			return "", 0, err
+		}
+	}
+	if strings.TrimSpace(instructions) == "" {
+		instructions = defaultPatchInstructions
+	}
+
+	reply, err := model.General(ctx, patch.Prompt(cfg.Prompts.Patch, instructions, sources))
+	if err != nil {
+		return "", 0, err
+	}
+	diffText := patch.Extract(reply)
+	files, err := patch.Parse(diffText)
+	if err != nil {
+		return reply, 0, err
+	}
+
+	// Apply in memory first, nothing is written before the preview is confirmed
+	var changes []fileChange
+	rejected := 0
+	for _, f := range files {
+		rel := filepath.ToSlash(filepath.Clean(f.Path()))
+		if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, "../") {
+			warnf(ctx, "Rejected %s: outside of the working directory", f.Path())
+			rejected += len(f.Hunks)
+			continue
+		}
+		path, known := inputs[rel]
+
+		switch {
+		case f.IsNew():
+			if _, err := os.Stat(rel); err == nil {
+				warnf(ctx, "Rejected %s: the diff creates it but it already exists", rel)
+				rejected += len(f.Hunks)
+				continue
+			}
+			res := patch.Apply("", f.Hunks)
+			changes = append(changes, fileChange{path: rel, after: res.Content, create: true})
+		case !known:
+			warnf(ctx, "Rejected %s: not one of the --file inputs", rel)
+			rejected += len(f.Hunks)
+		case f.IsDelete():
+			changes = append(changes, fileChange{path: path, before: sourceContent(sources, rel), delete: true})
+		default:
+			before := sourceContent(sources, rel)
+			res := patch.Apply(before, f.Hunks)
+			if res.Merged > 0 {
+				warnf(ctx, "%s: %d hunk(s) did not match exactly and were merged", rel, res.Merged)
+			}
+			for _, h := range res.Rejected {
+				warnf(ctx, "Rejected hunk in %s, its context was not found or conflicts with the file:\n%s", rel, strings.TrimRight(h.String(), "\n"))
+			}
+			rejected += len(res.Rejected)
+			if res.Content != before {
+				changes = append(changes, fileChange{path: path, before: before, after: res.Content})
+			}
+		}
+	}
+
+	if len(changes) == 0 {
+		infof(ctx, "No changes to apply")
+		return diffText, rejected, nil
+	}
+
+	for _, c := range changes {
+		switch {
+		case c.create:
+			fmt.Fprintf(os.Stderr, "=== %s (new file) ===\n", c.path)
+		case c.delete:
+			fmt.Fprintf(os.Stderr, "=== %s (delete) ===\n", c.path)
+			continue
+		default:
+			fmt.Fprintf(os.Stderr, "=== %s ===\n", c.path)
+		}
+		fmt.Fprintln(os.Stderr, renderDiff(c.before, c.after, "line"))
+	}
+
+	if !flags.Yes {
+		ok, err := cli.Confirm(fmt.Sprintf("Apply changes to %d file(s)?", len(changes)))
+		if err != nil {
+			return diffText, rejected, err
+		}
+		if !ok {
+			infof(ctx, "Aborted, no file changed")
+			return diffText, rejected, nil
+		}
+	}
+
+	for _, c := range changes {
+		if c.delete {
+			if err := os.Remove(c.path); err != nil {
+				return diffText, rejected, err
+			}
+			infof(ctx, "Deleted %s", c.path)
+			continue
+		}
+		// Patched files keep their mode (exec bit, stricter permissions), new ones get the default
+		if info, err := os.Stat(c.path); err == nil {
+			err = os.WriteFile(c.path, []byte(c.after), info.Mode().Perm())
+			if err != nil {
+				return diffText, rejected, err
+			}
+		} else if err := cli.WriteFile(c.path, []byte(c.after)); err != nil {
+			return diffText, rejected, err
+		}
+		infof(ctx, "Patched %s", c.path)
+	}
+	return diffText, rejected, nil
+}
+
+func sourceContent(sources []patch.Source, rel string) string {
+	for _, s := range sources {
+		if s.Path == rel {
+			return s.Content
+		}
+	}
+	return ""
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"ai/internal/cli"
+	"ai/internal/config"
+	"ai/internal/git"
+	"ai/internal/provider/ai"
+	"ai/internal/review"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// runReview asks the model for findings on a commit range (ai review main..HEAD), or on the
+// uncommitted changes when no range is given. Further arguments are pathspecs. The annotated
+// diff is sent in chunks of review.chunkKB so large changes stay within the request limits.
+func runReview(ctx context.Context, model ai.Provider, flags *cli.CMDFlags, cfg *config.Config) ([]review.Finding, error) {
+	rangeSpec, pathspecs := "HEAD", flags.Args
+	if len(pathspecs) > 0 && git.IsRevision(pathspecs[0]) {
+		rangeSpec, pathspecs = pathspecs[0], pathspecs[1:]
+	}
+	diff, err := git.Diff(rangeSpec, false, pathspecs, cfg.Review.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	files := git.Split(diff)
+	if len(files) == 0 {
+		return nil, errors.New("no changes to review in " + rangeSpec)
+	}
+
+	limitKB := cfg.Review.ChunkKB
+	if limitKB <= 0 {
+		limitKB = cfg.InputFileLimitKB
+	}
+	chunks := review.Chunks(files, limitKB*1024)
+
+	var findings []review.Finding
+	for i, chunk := range chunks {
+		if len(chunks) > 1 {
+			infof(ctx, "Reviewing part %d/%d", i+1, len(chunks))
+		}
+		reqCtx, cancel := requestContext(ctx, cfg)
+		reply, err := model.General(reqCtx, review.Prompt(cfg.Prompts.Review, flags.Input, chunk))
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		res, err := review.Parse(reply)
+		if err != nil {
+			return nil, fmt.Errorf("part %d/%d: %w", i+1, len(chunks), err)
+		}
+		findings = append(findings, res...)
+	}
+
+	valid, dropped := review.Validate(files, findings)
+	if dropped > 0 {
+		warnf(ctx, "Ignored %d finding(s) not on a line of the diff", dropped)
+	}
+	return valid, nil
+}
+
+// hasErrors reports whether a finding has the error severity
+func hasErrors(findings []review.Finding) bool {
+	for _, f := range findings {
+		if f.Severity == review.SeverityError {
+			return true
+		}
+	}
+	return false
+}
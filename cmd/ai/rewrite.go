@@ -0,0 +1,179 @@
+package main
+
+import (
+	"ai/internal/cli"
+	"ai/internal/config"
+	"ai/internal/diff"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+func checkOperationFlags(flags *cli.CMDFlags, cfg *config.Config) error {
+	if flags.Tone != "" || flags.Style != "" || flags.Length != "" {
+		if !flags.IsRewrite {
+			return errors.New("--tone, --style and --length require --rewrite")
+		}
+		if err := checkPreset("tone", flags.Tone, cfg.Rewrite.Tones); err != nil {
+			return err
+		}
+		if err := checkPreset("style", flags.Style, cfg.Rewrite.Styles); err != nil {
+			return err
+		}
+		if err := checkPreset("length", flags.Length, cfg.Rewrite.Lengths); err != nil {
+			return err
+		}
+	}
+
+	if flags.Format != "" || flags.Words != 0 || flags.Sentences != 0 {
+		if !flags.IsSummarize {
+			return errors.New("--format, --words and --sentences require --summarize")
+		}
+		if err := checkPreset("format", flags.Format, cfg.Summarize.Formats); err != nil {
+			return err
+		}
+		if flags.Words < 0 || flags.Sentences < 0 || (flags.Words > 0 && flags.Sentences > 0) {
+			return errors.New("use either a positive --words or --sentences target")
+		}
+	}
+
+	if flags.IsPatch && (flags.Compare != "" || flags.IsRewrite || flags.IsTranslate || flags.IsSummarize || flags.IsCheck || flags.IsExplain) {
+		return errors.New("--patch cannot be combined with --compare or another operation")
+	}
+
+	if flags.From != "" && !flags.IsTranslate {
+		return errors.New("--from requires --translate")
+	}
+
+	if flags.Sources && !flags.IsExplain {
+		return errors.New("--sources requires --explain")
+	}
+
+	if flags.IsAgent && (flags.Compare != "" || flags.Command != "" || flags.IsRewrite || flags.IsTranslate ||
+		flags.IsSummarize || flags.IsCheck || flags.IsPatch || flags.IsExplain || len(flags.Images) > 0) {
+		return errors.New("--agent answers a general prompt, it cannot be combined with other operations, --compare, batch or --image")
+	}
+
+	if !flags.Diff && !flags.InPlace {
+		return nil
+	}
+	if !flags.IsRewrite {
+		return errors.New("--diff and --in-place require --rewrite")
+	}
+	if flags.Compare != "" {
+		return errors.New("--diff and --in-place cannot be used with --compare")
+	}
+	if flags.DiffMode != "word" && flags.DiffMode != "line" {
+		return fmt.Errorf("unknown diff mode %q (word, line)", flags.DiffMode)
+	}
+	if flags.InPlace && (len(flags.Files) != 1 || flags.Input != "") {
+		return errors.New("--in-place requires a single --file and no --input")
+	}
+	if flags.InPlace {
+		if _, err := inPlacePath(flags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inPlacePath returns the file matched by the --file pattern, which must match exactly one
+func inPlacePath(flags *cli.CMDFlags) (string, error) {
+	files, err := cli.ExpandFiles(flags.Files)
+	if err != nil {
+		return "", err
+	}
+	if len(files) != 1 {
+		return "", fmt.Errorf("--in-place requires a single file, %s matches %d", flags.Files[0], len(files))
+	}
+	return files[0], nil
+}
+
+func checkPreset(kind, name string, presets map[string]string) error {
+	if name == "" {
+		return nil
+	}
+	if _, ok := presets[name]; ok {
+		return nil
+	}
+	var names []string
+	for n := range presets {
+		names = append(names, n)
+	}
+	slices.Sort(names)
+	return fmt.Errorf("unknown %s %q (%s)", kind, name, strings.Join(names, ", "))
+}
+
+// renderDiff shows the changes of the rewrite by word or line, or "No changes". A final
+// newline the model added or dropped is not a change.
+func renderDiff(original, rewritten, mode string) string {
+	original, rewritten = strings.TrimRight(original, "\n"), strings.TrimRight(rewritten, "\n")
+	if mode == "line" {
+		edits := diff.Lines(original, rewritten)
+		if !diff.Changed(edits) {
+			return "No changes"
+		}
+		return diff.RenderLines(edits, cli.UseColor())
+	}
+	edits := diff.Words(original, rewritten)
+	if !diff.Changed(edits) {
+		return "No changes"
+	}
+	return diff.RenderWords(edits, cli.UseColor())
+}
+
+// writeInPlace replaces the --file source with the rewrite, optionally keeping a .bak copy
+func writeInPlace(flags *cli.CMDFlags, original, rewritten string) error {
+	path, err := inPlacePath(flags)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", path)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if string(raw) != original {
+		return fmt.Errorf("%s is not a plain text file or changed since it was read", path)
+	}
+
+	// Models tend to drop the final newline
+	if strings.HasSuffix(original, "\n") && !strings.HasSuffix(rewritten, "\n") {
+		rewritten += "\n"
+	}
+	if rewritten == original {
+		fmt.Fprintln(os.Stderr, "No changes")
+		return nil
+	}
+
+	if !flags.Yes {
+		ok, err := cli.Confirm("Overwrite " + path + "?")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(os.Stderr, "Aborted, file not changed")
+			return nil
+		}
+	}
+
+	if flags.Backup {
+		if err := os.WriteFile(path+".bak", raw, info.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed to write backup: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, []byte(rewritten), info.Mode().Perm()); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "Updated", path)
+	return nil
+}
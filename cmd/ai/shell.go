@@ -0,0 +1,130 @@
+package main
+
+import (
+	"ai/internal/cli"
+	"ai/internal/config"
+	"ai/internal/provider/ai"
+	"ai/internal/shell"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+// runShell turns a request into a shell command (ai sh "list the largest files"), shows it with
+// an explanation and runs it after confirmation. Commands matching shell.denylist must be typed
+// back, even with --yes. With --follow-up, the output is sent back with the next request.
+// It returns the exit code of the last command run.
+func runShell(flags *cli.CMDFlags, cfg *config.Config) (int, error) {
+	request := strings.TrimSpace(strings.Join(flags.Args, " "))
+	if request == "" {
+		request = strings.TrimSpace(flags.Input)
+	}
+	if request == "" {
+		return 0, errors.New(`usage: ai sh "<what the command should do>"`)
+	}
+	denylist, err := shell.NewDenylist(cfg.Shell.Denylist)
+	if err != nil {
+		return 0, err
+	}
+	model, err := newProvider(flags.Provider, cfg)
+	if err != nil {
+		return 0, err
+	}
+
+	var history []shell.Turn
+	exitCode := 0
+	for {
+		s, err := suggestCommand(model, cfg, request, history)
+		if err != nil {
+			return exitCode, err
+		}
+		if s.Command == "" {
+			fmt.Println(s.Explanation)
+		} else {
+			turn, ran, err := confirmAndRun(flags, cfg, denylist, s)
+			if err != nil {
+				return exitCode, err
+			}
+			if ran {
+				turn.Request = request
+				history = append(history, turn)
+				exitCode = turn.ExitCode
+			}
+		}
+
+		if !flags.FollowUp {
+			return exitCode, nil
+		}
+		if request, err = cli.Ask("Follow-up (empty to quit): "); err != nil || request == "" {
+			return exitCode, err
+		}
+	}
+}
+
+func suggestCommand(model ai.Provider, cfg *config.Config, request string, history []shell.Turn) (shell.Suggestion, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.HttpTimeoutSeconds)*time.Second)
+	defer cancel()
+	reply, err := model.General(ctx, shell.Prompt(cfg.Prompts.Shell, request, history))
+	if err != nil {
+		return shell.Suggestion{}, err
+	}
+	return shell.Parse(reply)
+}
+
+// confirmAndRun shows the command and runs it once confirmed. Declined commands are printed
+// on stdout so they can be copied or edited.
+func confirmAndRun(flags *cli.CMDFlags, cfg *config.Config, denylist shell.Denylist, s shell.Suggestion) (shell.Turn, bool, error) {
+	fmt.Fprintf(os.Stderr, "$ %s\n", s.Command)
+	if s.Explanation != "" {
+		fmt.Fprintf(os.Stderr, "  %s\n", s.Explanation)
+	}
+
+	var ok bool
+	if pattern := denylist.Match(s.Command); pattern != "" {
+		fmt.Fprintf(os.Stderr, "Warning: the command matches the destructive pattern %s\n", pattern)
+		typed, err := cli.Ask("Type the command to run it: ")
+		if err != nil {
+			return shell.Turn{}, false, err
+		}
+		ok = typed == s.Command
+	} else if flags.Yes {
+		ok = true
+	} else {
+		var err error
+		if ok, err = cli.Confirm("Run it?"); err != nil {
+			return shell.Turn{}, false, err
+		}
+	}
+	if !ok {
+		fmt.Fprintln(os.Stderr, "Not run")
+		fmt.Println(s.Command)
+		return shell.Turn{}, false, nil
+	}
+
+	// Ctrl+C stops the command, not ai
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	defer signal.Stop(signals)
+
+	output := &shell.TailBuffer{Limit: cfg.InputFileLimitKB * 1024}
+	cmd := shell.Command(s.Command)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = io.MultiWriter(os.Stdout, output)
+	cmd.Stderr = io.MultiWriter(os.Stderr, output)
+	turn := shell.Turn{Command: s.Command}
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return turn, false, fmt.Errorf("error running command: %w", err)
+		}
+		turn.ExitCode = exitErr.ExitCode()
+	}
+	turn.Output = output.String()
+	return turn, true, nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"ai/internal/config"
+	"ai/internal/provider/ai"
+	"ai/internal/subtitle"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// translateSubtitles translates only the cue text, in batches of cues, so indexes,
+// timestamps and other blocks are re-emitted exactly as they were. Each request has its own timeout.
+func translateSubtitles(ctx context.Context, model ai.Provider, cfg *config.Config, sub *subtitle.File, lang string) (string, error) {
+	out := sub.Clone()
+	cues := out.Cues()
+	batchSize := max(cfg.Subtitles.BatchCues, 1)
+
+	for start := 0; start < len(cues); start += batchSize {
+		batch := cues[start:min(start+batchSize, len(cues))]
+		texts := make([]string, len(batch))
+		for i, idx := range batch {
+			texts[i] = strings.Join(out.Blocks[idx].Text, "\n")
+		}
+
+		translated, err := sendSegments(ctx, texts, timed(cfg, func(ctx context.Context, text string) (string, error) {
+			return model.Translate(ctx, text, lang)
+		}), nil)
+		if err != nil {
+			return "", fmt.Errorf("cues %d-%d: %w", start+1, start+len(batch), err)
+		}
+		for i, idx := range batch {
+			out.Blocks[idx].Text = strings.Split(translated[i], "\n")
+		}
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"ai/internal/cli"
+	"ai/internal/config"
+	"ai/internal/glossary"
+	"ai/internal/provider/ai"
+	"ai/internal/subtitle"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+type translation struct {
+	Language string `json:"language"`
+	Text     string `json:"text"`
+}
+
+// loadGlossary reads the --glossary file, falling back to the glossary path of config.yaml,
+// and warns about target languages it has no translations for
+func loadGlossary(ctx context.Context, flags *cli.CMDFlags, cfg *config.Config) (*glossary.Glossary, error) {
+	path := flags.Glossary
+	if path == "" {
+		path = cfg.Glossary
+	}
+	if path == "" || !flags.IsTranslate {
+		return nil, nil
+	}
+	terms, err := glossary.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, lang := range targetLanguages(flags) {
+		if !terms.HasLanguage(lang) {
+			warnf(ctx, "Glossary %s has no translations for %s, only its do-not-translate terms apply", path, lang)
+		}
+	}
+	return terms, nil
+}
+
+// targetLanguages splits -l fr,de,ja into its languages, defaulting to defaultTargetLanguage
+func targetLanguages(flags *cli.CMDFlags) []string {
+	var langs []string
+	for _, lang := range strings.Split(flags.Language, ",") {
+		if lang = strings.TrimSpace(lang); lang != "" {
+			langs = append(langs, lang)
+		}
+	}
+	if len(langs) == 0 {
+		return []string{defaultTargetLanguage}
+	}
+	return langs
+}
+
+// runTranslate detects the source language (unless --from is set), reports it on stderr and
+// returns it, and translates the input into every target language concurrently. Glossary
+// violations are reported on stderr.
+func runTranslate(ctx context.Context, model ai.Provider, flags *cli.CMDFlags, cfg *config.Config, input string, terms *glossary.Glossary) ([]translation, string, error) {
+	// Subtitle files go through a format-aware pipeline that only translates the cue text,
+	// Markdown documents through one that only translates the prose
+	var sub *subtitle.File
+	sample := input
+	if len(flags.Files) == 1 && subtitle.IsSubtitle(flags.Files[0]) {
+		if flags.Input != "" {
+			return nil, "", fmt.Errorf("--input cannot be combined with a subtitle file")
+		}
+		var err error
+		if sub, err = subtitle.Parse(input); err != nil {
+			return nil, "", err
+		}
+		sample = sub.Text()
+	}
+
+	source := flags.From
+	if source == "" {
+		reqCtx, cancel := requestContext(ctx, cfg)
+		lang, err := model.DetectLanguage(reqCtx, sample)
+		cancel()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to detect source language: %w", err)
+		}
+		source = strings.TrimSpace(lang)
+		infof(ctx, "Detected source language: %s", source)
+	}
+
+	langs := targetLanguages(flags)
+	results := make([]translation, len(langs))
+	errs := make([]error, len(langs))
+	var wg sync.WaitGroup
+	for i, lang := range langs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var text string
+			var err error
+			translate := func(ctx context.Context, text string) (string, error) {
+				return model.Translate(ctx, text, lang)
+			}
+			switch {
+			case sub != nil:
+				text, err = translateSubtitles(ctx, model, cfg, sub, lang)
+			case markdownInput(flags):
+				text, err = processMarkdown(ctx, input, cfg, translate)
+			default:
+				reqCtx, cancel := requestContext(ctx, cfg)
+				text, err = translate(reqCtx, input)
+				cancel()
+			}
+			results[i] = translation{Language: lang, Text: text}
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", lang, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	for _, t := range results {
+		for _, v := range terms.Verify(sample, t.Text, t.Language) {
+			warnf(ctx, "Glossary violation (%s): %s", t.Language, v)
+		}
+	}
+	return results, source, nil
+}
+
+// joinTranslations labels each translation when there is more than one
+func joinTranslations(translations []translation) string {
+	if len(translations) == 1 {
+		return translations[0].Text
+	}
+	var parts []string
+	for _, t := range translations {
+		parts = append(parts, "=== "+t.Language+" ===\n"+strings.TrimSpace(t.Text))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// translationPath inserts the language before the extension: out/readme.md -> out/readme.fr.md
+func translationPath(name, lang string) string {
+	ext := filepath.Ext(name)
+	lang = strings.ToLower(strings.ReplaceAll(lang, " ", "-"))
+	return strings.TrimSuffix(name, ext) + "." + lang + ext
+}
+
+func writeTranslations(name string, translations []translation) error {
+	for _, t := range translations {
+		path := translationPath(name, t.Language)
+		if err := cli.WriteFile(path, []byte(t.Text)); err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "Wrote", path)
+	}
+	return nil
+}
@@ -0,0 +1,505 @@
+package agent
+
+import (
+	"ai/internal/cli"
+	"ai/internal/provider/ai"
+	"ai/internal/shell"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Limits of the tool outputs sent back to the model
+const (
+	maxEntries    = 500
+	maxMatches    = 100
+	maxMatchWidth = 200
+)
+
+// Toolbox runs the built-in tools on the files of the current directory. Files ignored by
+// .gitignore or .aiignore, the .git directory and symlinks leading outside are out of reach.
+type Toolbox struct {
+	FileLimitKB     int
+	DocumentLimitKB int
+	AllowedCommands []string // command prefixes, e.g. "go test" or "ls"
+	CommandTimeout  time.Duration
+	ignore          *cli.IgnoreList
+	root            string // current directory with symlinks resolved
+}
+
+// New returns a toolbox with the ignore rules of the current directory
+func New(fileLimitKB, documentLimitKB int, allowed []string, timeout time.Duration) (*Toolbox, error) {
+	ignore, err := cli.LoadIgnore(".")
+	if err != nil {
+		return nil, err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	root, err := filepath.EvalSymlinks(wd)
+	if err != nil {
+		return nil, err
+	}
+	return &Toolbox{
+		FileLimitKB:     fileLimitKB,
+		DocumentLimitKB: documentLimitKB,
+		AllowedCommands: allowed,
+		CommandTimeout:  timeout,
+		ignore:          ignore,
+		root:            root,
+	}, nil
+}
+
+// deniedFlags write files, run other programs or change the directory of an allowed command.
+// Names are compared without their dashes, long options also by prefix as git accepts them.
+var deniedFlags = []string{
+	"o", "C", "exec", "toolexec", "vettool", "overlay", "modfile", "output", "outputdir", "ext-diff", "textconv",
+	"upload-pack", "receive-pack", "coverprofile", "cpuprofile", "memprofile", "blockprofile", "mutexprofile", "trace",
+}
+
+// runsPrograms are subcommands that run or configure other programs. A broad allowlist entry
+// such as "go" does not cover them, they must be allowed by name, e.g. "go run".
+var runsPrograms = []string{
+	"go run", "go generate", "go tool", "go env", "git -c", "git --config-env", "git config",
+	"npm run", "npm exec", "npx", "cargo run",
+}
+
+// args of all the tools, each tool reads its own
+type args struct {
+	Path      string `json:"path"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Pattern   string `json:"pattern"`
+	Glob      string `json:"glob"`
+	Command   string `json:"command"`
+}
+
+// Tools returns the definitions sent to the model
+func (t *Toolbox) Tools() []ai.Tool {
+	str := func(description string) map[string]any {
+		return map[string]any{"type": "string", "description": description}
+	}
+	integer := func(description string) map[string]any {
+		return map[string]any{"type": "integer", "description": description}
+	}
+	object := func(properties map[string]any, required ...string) map[string]any {
+		schema := map[string]any{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	}
+
+	tools := []ai.Tool{
+		{
+			Name:        "read_file",
+			Description: "Read a text file (or the text of a PDF/DOCX document) of the project, optionally only a range of lines.",
+			Parameters: object(map[string]any{
+				"path":       str("File path relative to the project root"),
+				"start_line": integer("First line to read, 1-based (optional)"),
+				"end_line":   integer("Last line to read (optional)"),
+			}, "path"),
+		},
+		{
+			Name:        "list_directory",
+			Description: "List the files and subdirectories of a project directory, directories end with /.",
+			Parameters: object(map[string]any{
+				"path": str("Directory path relative to the project root, . for the root"),
+			}),
+		},
+		{
+			Name:        "grep",
+			Description: "Search the project files for a regular expression (Go RE2 syntax), returns path:line: text matches.",
+			Parameters: object(map[string]any{
+				"pattern": str("Regular expression to search for"),
+				"path":    str("Directory or file to search in, defaults to the project root"),
+				"glob":    str("Only search files whose name matches this glob, e.g. *.go (optional)"),
+			}, "pattern"),
+		},
+	}
+	if len(t.AllowedCommands) > 0 {
+		tools = append(tools, ai.Tool{
+			Name: "run_command",
+			Description: "Run a command in the project root and return its output and exit status. No shell is used: " +
+				"pipes, redirections and quotes are not interpreted. Allowed commands: " + strings.Join(t.AllowedCommands, ", ") + ".",
+			Parameters: object(map[string]any{
+				"command": str("Command line, e.g. go test ./..."),
+			}, "command"),
+		})
+	}
+	return tools
+}
+
+// Check validates a call before it is shown for approval, so calls that cannot run are
+// reported to the model without asking
+func (t *Toolbox) Check(call ai.ToolCall) error {
+	a, err := parseArgs(call)
+	if err != nil {
+		return err
+	}
+	switch call.Name {
+	case "read_file":
+		_, err = t.resolve(a.Path)
+	case "list_directory", "grep":
+		_, err = t.resolve(a.Path)
+		if err == nil && call.Name == "grep" {
+			_, err = regexp.Compile(a.Pattern)
+		}
+	case "run_command":
+		_, err = t.command(a.Command)
+	default:
+		err = fmt.Errorf("unknown tool %q", call.Name)
+	}
+	return err
+}
+
+// Run executes a call and returns the output for the model
+func (t *Toolbox) Run(ctx context.Context, call ai.ToolCall) (string, error) {
+	if err := t.Check(call); err != nil {
+		return "", err
+	}
+	a, _ := parseArgs(call)
+	switch call.Name {
+	case "read_file":
+		return t.readFile(a)
+	case "list_directory":
+		return t.listDirectory(a)
+	case "grep":
+		return t.grep(a)
+	default:
+		return t.runCommand(ctx, a)
+	}
+}
+
+// ApprovalKey returns what an "always" answer approves: the exact command line for
+// run_command, the tool for the others, which cannot leave the project
+func ApprovalKey(call ai.ToolCall) string {
+	if call.Name != "run_command" {
+		return call.Name
+	}
+	a, err := parseArgs(call)
+	if err != nil {
+		return call.Name + " " + string(call.Args)
+	}
+	return strings.Join(strings.Fields(a.Command), " ")
+}
+
+// ReadOnly reports whether the call only reads the project, --yes approves these calls
+func ReadOnly(call ai.ToolCall) bool {
+	return call.Name != "run_command"
+}
+
+// Describe returns a one line summary of a call for the approval prompt
+func Describe(call ai.ToolCall) string {
+	a, err := parseArgs(call)
+	if err != nil {
+		return call.Name + " " + string(call.Args)
+	}
+	switch call.Name {
+	case "read_file":
+		if a.StartLine > 0 || a.EndLine > 0 {
+			return fmt.Sprintf("read_file %s (lines %d-%d)", a.Path, a.StartLine, a.EndLine)
+		}
+		return "read_file " + a.Path
+	case "list_directory":
+		return "list_directory " + orDefault(a.Path, ".")
+	case "grep":
+		desc := fmt.Sprintf("grep %q in %s", a.Pattern, orDefault(a.Path, "."))
+		if a.Glob != "" {
+			desc += " (" + a.Glob + ")"
+		}
+		return desc
+	case "run_command":
+		return "run_command " + a.Command
+	}
+	return call.Name + " " + string(call.Args)
+}
+
+func parseArgs(call ai.ToolCall) (args, error) {
+	var a args
+	if len(call.Args) == 0 {
+		return a, nil
+	}
+	if err := json.Unmarshal(call.Args, &a); err != nil {
+		return a, fmt.Errorf("invalid arguments: %w", err)
+	}
+	return a, nil
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// resolve returns the path relative to the current directory, refusing paths outside of it,
+// inside .git or ignored, also once symlinks are followed
+func (t *Toolbox) resolve(name string) (string, error) {
+	rel := cli.RelPath(filepath.FromSlash(orDefault(name, ".")))
+	if outside(rel) {
+		return "", fmt.Errorf("%s is outside of the project", name)
+	}
+	if rel == "." {
+		return rel, nil
+	}
+	info, err := os.Stat(rel)
+	if err != nil {
+		return "", err
+	}
+	if t.ignored(rel, info.IsDir()) {
+		return "", fmt.Errorf("%s is ignored", name)
+	}
+
+	real, err := filepath.EvalSymlinks(rel)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(real) {
+		real = filepath.Join(t.root, real)
+	}
+	target, err := filepath.Rel(t.root, real)
+	if err != nil || outside(filepath.ToSlash(target)) {
+		return "", fmt.Errorf("%s links outside of the project", name)
+	}
+	if target = filepath.ToSlash(target); target != rel && target != "." && t.ignored(target, info.IsDir()) {
+		return "", fmt.Errorf("%s links to an ignored file", name)
+	}
+	return rel, nil
+}
+
+func outside(rel string) bool {
+	return rel == ".." || strings.HasPrefix(rel, "../") || filepath.IsAbs(rel)
+}
+
+// ignored reports whether the path or one of its parent directories is .git or ignored
+func (t *Toolbox) ignored(rel string, isDir bool) bool {
+	parts := strings.Split(rel, "/")
+	for i := range parts {
+		prefix := strings.Join(parts[:i+1], "/")
+		if parts[i] == ".git" || t.ignore.Ignored(prefix, i < len(parts)-1 || isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Toolbox) readFile(a args) (string, error) {
+	rel, _ := t.resolve(a.Path)
+	text, err := cli.ReadFile(rel, t.FileLimitKB, t.DocumentLimitKB, false)
+	if err != nil {
+		return "", err
+	}
+	if a.StartLine <= 0 && a.EndLine <= 0 {
+		return text, nil
+	}
+	lines := strings.Split(text, "\n")
+	start, end := max(a.StartLine, 1), len(lines)
+	if a.EndLine > 0 {
+		end = min(a.EndLine, len(lines))
+	}
+	if start > end {
+		return "", fmt.Errorf("%s has %d lines", rel, len(lines))
+	}
+	return strings.Join(lines[start-1:end], "\n"), nil
+}
+
+func (t *Toolbox) listDirectory(a args) (string, error) {
+	rel, _ := t.resolve(a.Path)
+	entries, err := os.ReadDir(rel)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	n := 0
+	for _, e := range entries {
+		name := path.Join(rel, e.Name())
+		if e.Name() == ".git" || t.ignore.Ignored(name, e.IsDir()) {
+			continue
+		}
+		if n++; n > maxEntries {
+			fmt.Fprintf(&b, "[... more than %d entries]\n", maxEntries)
+			break
+		}
+		if e.IsDir() {
+			b.WriteString(e.Name() + "/\n")
+		} else {
+			b.WriteString(e.Name() + "\n")
+		}
+	}
+	if b.Len() == 0 {
+		return "(empty directory)", nil
+	}
+	return b.String(), nil
+}
+
+var errEnoughMatches = errors.New("enough matches")
+
+func (t *Toolbox) grep(a args) (string, error) {
+	root, _ := t.resolve(a.Path)
+	re, _ := regexp.Compile(a.Pattern)
+
+	var b strings.Builder
+	matches := 0
+	err := filepath.WalkDir(root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // unreadable entries are skipped
+		}
+		rel := filepath.ToSlash(name)
+		if name != root && (d.Name() == ".git" || t.ignore.Ignored(rel, d.IsDir())) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || d.Type()&fs.ModeSymlink != 0 {
+			return nil // symlinks may lead outside of the project
+		}
+		if a.Glob != "" {
+			if ok, _ := path.Match(a.Glob, d.Name()); !ok {
+				return nil
+			}
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > int64(t.FileLimitKB*1024) {
+			return nil
+		}
+		data, err := os.ReadFile(name)
+		if err != nil || bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+			return nil // binary file
+		}
+		for i, line := range strings.Split(string(data), "\n") {
+			if !re.MatchString(line) {
+				continue
+			}
+			if matches++; matches > maxMatches {
+				fmt.Fprintf(&b, "[... more than %d matches]\n", maxMatches)
+				return errEnoughMatches
+			}
+			if len(line) > maxMatchWidth {
+				line = line[:maxMatchWidth] + "..."
+			}
+			fmt.Fprintf(&b, "%s:%d: %s\n", rel, i+1, strings.TrimRight(line, "\r"))
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errEnoughMatches) {
+		return "", err
+	}
+	if matches == 0 {
+		return "No matches", nil
+	}
+	return b.String(), nil
+}
+
+// command splits the command line and checks it against the allowlist. The arguments may
+// not name files outside of the project or use flags that write files or run programs.
+func (t *Toolbox) command(line string) ([]string, error) {
+	words := strings.Fields(line)
+	if len(words) == 0 {
+		return nil, errors.New("empty command")
+	}
+	allowed := 0 // words of the longest matching allowlist entry
+	for _, cmd := range t.AllowedCommands {
+		if prefix := strings.Fields(cmd); len(prefix) > allowed && hasWords(words, prefix) {
+			allowed = len(prefix)
+		}
+	}
+	if allowed == 0 {
+		return nil, fmt.Errorf("command not allowed, allowed commands: %s", strings.Join(t.AllowedCommands, ", "))
+	}
+	for _, cmd := range runsPrograms {
+		if prefix := strings.Fields(cmd); hasWords(words, prefix) && allowed < len(prefix) {
+			return nil, fmt.Errorf("%s runs other programs, it must be listed in agent.allowedCommands", cmd)
+		}
+	}
+
+	for _, word := range words[1:] {
+		value := word
+		if strings.HasPrefix(word, "-") {
+			name, v, _ := strings.Cut(word, "=")
+			if deniedFlag(name) {
+				return nil, fmt.Errorf("flag %s is not allowed", name)
+			}
+			value = v
+		}
+		if value == "" {
+			continue
+		}
+		if strings.HasPrefix(value, "~") || outside(path.Clean(filepath.ToSlash(value))) {
+			return nil, fmt.Errorf("argument %s is outside of the project", word)
+		}
+		if _, err := os.Lstat(value); err == nil {
+			if _, err := t.resolve(value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return words, nil
+}
+
+// hasWords reports whether the command starts with the words of prefix. A flag of the prefix
+// also matches with a value: "git --config-env" matches git --config-env=x.
+func hasWords(words, prefix []string) bool {
+	if len(prefix) == 0 || len(words) < len(prefix) {
+		return false
+	}
+	for i, p := range prefix {
+		if name, _, _ := strings.Cut(words[i], "="); words[i] != p && !(strings.HasPrefix(p, "-") && name == p) {
+			return false
+		}
+	}
+	return true
+}
+
+func deniedFlag(flag string) bool {
+	name := strings.TrimLeft(flag, "-")
+	long := strings.HasPrefix(flag, "--")
+	for _, d := range deniedFlags {
+		if name == d || long && len(name) > 1 && strings.HasPrefix(d, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Toolbox) runCommand(ctx context.Context, a args) (string, error) {
+	words, _ := t.command(a.Command)
+	if t.CommandTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.CommandTimeout)
+		defer cancel()
+	}
+
+	output := &shell.TailBuffer{Limit: t.FileLimitKB * 1024}
+	cmd := exec.CommandContext(ctx, words[0], words[1:]...)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	err := cmd.Run()
+	status := "[exit status 0]"
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr) && ctx.Err() != nil:
+		status = "[killed after " + t.CommandTimeout.String() + "]"
+	case errors.As(err, &exitErr):
+		status = fmt.Sprintf("[exit status %d]", exitErr.ExitCode())
+	default:
+		return "", err
+	}
+	if out := strings.TrimRight(output.String(), "\n"); out != "" {
+		return out + "\n" + status, nil
+	}
+	return status, nil
+}
@@ -0,0 +1,210 @@
+package agent
+
+import (
+	"ai/internal/provider/ai"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// project creates a directory with a few files and changes into it
+func project(t *testing.T) *Toolbox {
+	t.Helper()
+	outsideDir := t.TempDir()
+	os.WriteFile(filepath.Join(outsideDir, "secret.txt"), []byte("secret\n"), 0644)
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	files := map[string]string{
+		".gitignore":    ".env\nbuild/\n",
+		"main.go":       "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n",
+		"docs/guide.md": "# Guide\nhello docs\n",
+		".env":          "TOKEN=1\n",
+		"build/out":     "binary\n",
+	}
+	for name, content := range files {
+		os.MkdirAll(filepath.Dir(name), 0755)
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.Symlink(filepath.Join(outsideDir, "secret.txt"), "leak.txt")
+	os.Symlink(".env", "env.txt")
+	os.Symlink("main.go", "link.go")
+
+	tb, err := New(64, 1024, []string{"go test", "ls", "git diff"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tb
+}
+
+func call(name string, a args) ai.ToolCall {
+	data, _ := json.Marshal(a)
+	return ai.ToolCall{Name: name, Args: data}
+}
+
+func TestResolve(t *testing.T) {
+	tb := project(t)
+	tests := []struct {
+		path string
+		ok   bool
+	}{
+		{"main.go", true},
+		{"", true},
+		{"./docs/../main.go", true},
+		{"docs", true},
+		{"link.go", true},
+		{"../x", false},
+		{"/etc/passwd", false},
+		{".env", false},
+		{"build/out", false},
+		{".git/config", false},
+		{"leak.txt", false},
+		{"env.txt", false},
+		{"missing.go", false},
+	}
+	for _, tt := range tests {
+		if _, err := tb.resolve(tt.path); (err == nil) != tt.ok {
+			t.Errorf("resolve(%q) = %v", tt.path, err)
+		}
+	}
+}
+
+func TestCommand(t *testing.T) {
+	tb := project(t)
+	tests := []struct {
+		line string
+		ok   bool
+	}{
+		{"go test ./...", true},
+		{"go test  -run TestX -v .", true},
+		{"ls docs", true},
+		{"git diff --stat", true},
+		{"go build ./...", false},
+		{"rm -rf .", false},
+		{"", false},
+		{"go test -o /tmp/x .", false},
+		{"go test -exec=sh .", false},
+		{"go test --toolexec sh", false},
+		{"git diff --out=patch", false},
+		{"git diff --ext", false},
+		{"git diff -C /", false},
+		{"ls /etc", false},
+		{"ls ../", false},
+		{"ls ~", false},
+		{"go test -coverprofile=../c.out", false},
+		{"ls .env", false},
+		{"ls leak.txt", false},
+		{"go vet -vettool=./x ./...", false},
+		{"go vet --vettool ./x", false},
+		{"git diff --upload-pack=./x", false},
+	}
+	for _, tt := range tests {
+		if _, err := tb.command(tt.line); (err == nil) != tt.ok {
+			t.Errorf("command(%q) = %v", tt.line, err)
+		}
+	}
+}
+
+func TestRunsPrograms(t *testing.T) {
+	project(t)
+	broad, err := New(64, 1024, []string{"go", "git", "npm test"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	explicit, err := New(64, 1024, []string{"go", "go run", "git config"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		tb   *Toolbox
+		line string
+		ok   bool
+	}{
+		{broad, "go test ./...", true},
+		{broad, "go version", true},
+		{broad, "go run ./cmd/x", false},
+		{broad, "go generate ./...", false},
+		{broad, "go tool ./x", false},
+		{broad, "go env -w GOFLAGS=-toolexec=./x", false},
+		{broad, "git -c core.pager=./x log", false},
+		{broad, "git --config-env=core.pager=X log", false},
+		{broad, "git config core.pager ./x", false},
+		{broad, "git log", true},
+		{broad, "npm exec x", false},
+		{explicit, "go run ./cmd/x", true},
+		{explicit, "go generate ./...", false},
+		{explicit, "git config user.name", true},
+	}
+	for _, tt := range tests {
+		if _, err := tt.tb.command(tt.line); (err == nil) != tt.ok {
+			t.Errorf("command(%q) with %q = %v", tt.line, tt.tb.AllowedCommands, err)
+		}
+	}
+}
+
+func TestDeniedFlag(t *testing.T) {
+	for flag, want := range map[string]bool{"-o": true, "--output": true, "--outp": true, "--o": true, "-v": false, "--stat": false, "-C": true, "-c": false, "--ext-diff": true, "-vettool": true, "--upload-pack": true} {
+		if got := deniedFlag(flag); got != want {
+			t.Errorf("deniedFlag(%q) = %v", flag, got)
+		}
+	}
+}
+
+func TestApprovalKey(t *testing.T) {
+	tests := []struct {
+		call ai.ToolCall
+		want string
+	}{
+		{call("read_file", args{Path: "main.go"}), "read_file"},
+		{call("grep", args{Pattern: "x"}), "grep"},
+		{call("run_command", args{Command: "go  test ./..."}), "go test ./..."},
+		{call("run_command", args{Command: "go test -run X"}), "go test -run X"},
+	}
+	for _, tt := range tests {
+		if got := ApprovalKey(tt.call); got != tt.want {
+			t.Errorf("ApprovalKey(%s) = %q, want %q", tt.call.Args, got, tt.want)
+		}
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	for name, want := range map[string]bool{"read_file": true, "list_directory": true, "grep": true, "run_command": false} {
+		if got := ReadOnly(call(name, args{})); got != want {
+			t.Errorf("ReadOnly(%s) = %v", name, got)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	tb := project(t)
+	tests := []struct {
+		call ai.ToolCall
+		want string
+	}{
+		{call("read_file", args{Path: "main.go", StartLine: 3, EndLine: 4}), "func main() {\n\tprintln(\"hello\")"},
+		{call("list_directory", args{}), ".gitignore\ndocs/\nenv.txt\nleak.txt\nlink.go\nmain.go\n"},
+		{call("grep", args{Pattern: "hello"}), "docs/guide.md:2: hello docs\nmain.go:4: \tprintln(\"hello\")\n"},
+		{call("grep", args{Pattern: "hello", Glob: "*.md"}), "docs/guide.md:2: hello docs\n"},
+		{call("grep", args{Pattern: "TOKEN|secret"}), "No matches"},
+		{call("run_command", args{Command: "ls docs"}), "guide.md\n[exit status 0]"},
+	}
+	for _, tt := range tests {
+		got, err := tb.Run(context.Background(), tt.call)
+		if err != nil {
+			t.Errorf("%s %s: %v", tt.call.Name, tt.call.Args, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s %s = %q, want %q", tt.call.Name, tt.call.Args, got, tt.want)
+		}
+	}
+
+	if _, err := tb.Run(context.Background(), call("read_file", args{Path: "leak.txt"})); err == nil || !strings.Contains(err.Error(), "outside") {
+		t.Errorf("reading a symlink leading outside: %v", err)
+	}
+}
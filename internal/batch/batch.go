@@ -0,0 +1,205 @@
+package batch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Item is a single unit of work, either a file or a record of a JSONL input
+type Item struct {
+	ID    string `json:"id"`
+	Path  string `json:"-"` // source file, empty for JSONL records
+	Input string `json:"input"`
+}
+
+const (
+	StatusOK     = "ok"
+	StatusFailed = "failed"
+)
+
+// Result is written as one line of the JSONL results file
+type Result struct {
+	ID     string `json:"id"`
+	Path   string `json:"path,omitempty"`
+	Status string `json:"status"`
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type Summary struct {
+	Total     int
+	Succeeded int
+	Failed    int
+	Skipped   int
+	Failures  []Result
+}
+
+type Options struct {
+	Workers int
+}
+
+// ProcessFunc runs the operation for one item and returns its output
+type ProcessFunc func(ctx context.Context, item Item) (string, error)
+
+// Run processes items concurrently. onResult is called for every finished item
+// from a single goroutine, so it may write files without extra locking.
+// Once ctx is cancelled, remaining items are left out and can be resumed later.
+func Run(ctx context.Context, items []Item, opts Options, process ProcessFunc, onResult func(Result)) Summary {
+	workers := max(opts.Workers, 1)
+
+	jobs := make(chan Item)
+	results := make(chan Result)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range jobs {
+				res := Result{ID: item.ID, Path: item.Path}
+				output, err := process(ctx, item)
+				if err != nil {
+					res.Status = StatusFailed
+					res.Error = err.Error()
+				} else {
+					res.Status = StatusOK
+					res.Output = output
+				}
+				results <- res
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, item := range items {
+			select {
+			case jobs <- item:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	summary := Summary{Total: len(items)}
+	for res := range results {
+		if res.Status == StatusOK {
+			summary.Succeeded++
+		} else {
+			summary.Failed++
+			summary.Failures = append(summary.Failures, res)
+		}
+		onResult(res)
+	}
+	summary.Skipped = summary.Total - summary.Succeeded - summary.Failed
+	return summary
+}
+
+// LoadRecords reads a JSONL file of {"id": ..., "input": ...} records.
+// Records without an id are numbered by their line.
+func LoadRecords(path string) ([]Item, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read records: %w", err)
+	}
+	defer file.Close()
+
+	var items []Item
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var item Item
+		if err := json.Unmarshal(scanner.Bytes(), &item); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid record: %w", path, line, err)
+		}
+		if item.ID == "" {
+			item.ID = strconv.Itoa(line)
+		}
+		items = append(items, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read records: %w", err)
+	}
+	return items, nil
+}
+
+// Completed returns the ids that succeeded in a previous results file
+func Completed(resultsPath string) (map[string]bool, error) {
+	done := map[string]bool{}
+	file, err := os.Open(resultsPath)
+	if os.IsNotExist(err) {
+		return done, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var res Result
+		if err := json.Unmarshal(scanner.Bytes(), &res); err != nil {
+			continue // a partially written line from an interrupted run
+		}
+		if res.Status == StatusOK {
+			done[res.ID] = true
+		}
+	}
+	return done, scanner.Err()
+}
+
+// OutputPaths maps every item id to its output file under outDir, mirroring its relative path.
+// Files outside the current directory keep their path without the leading ../ or root, and
+// records are named after their id with a .txt extension, records whose id is no local file
+// name are left out. Paths taken by an earlier item get a -2, -3, ... suffix.
+func OutputPaths(outDir string, items []Item) map[string]string {
+	paths := map[string]string{}
+	taken := map[string]bool{}
+	for _, item := range items {
+		if _, ok := paths[item.ID]; ok {
+			continue
+		}
+		rel := filepath.Clean(filepath.FromSlash(item.ID))
+		if item.Path == "" {
+			if rel += ".txt"; !filepath.IsLocal(rel) {
+				continue
+			}
+		} else if !filepath.IsLocal(rel) {
+			rel = localPart(rel)
+		}
+
+		ext := filepath.Ext(rel)
+		name := rel
+		for n := 2; taken[strings.ToLower(name)]; n++ {
+			name = strings.TrimSuffix(rel, ext) + "-" + strconv.Itoa(n) + ext
+		}
+		taken[strings.ToLower(name)] = true
+		paths[item.ID] = filepath.Join(outDir, name)
+	}
+	return paths
+}
+
+// localPart drops the volume, root and leading .. elements of a path
+func localPart(path string) string {
+	parts := strings.Split(strings.TrimPrefix(path, filepath.VolumeName(path)), string(filepath.Separator))
+	for len(parts) > 1 && (parts[0] == "" || parts[0] == "..") {
+		parts = parts[1:]
+	}
+	return filepath.Join(parts...)
+}
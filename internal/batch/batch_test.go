@@ -0,0 +1,42 @@
+package batch
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestOutputPaths(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []Item
+		want  map[string]string // id to path under out, slash separated
+	}{
+		{"local file", []Item{{ID: "docs/a.md", Path: "docs/a.md"}}, map[string]string{"docs/a.md": "docs/a.md"}},
+		{"outside files keep their directories",
+			[]Item{{ID: "../a/x.md", Path: "../a/x.md"}, {ID: "../b/x.md", Path: "../b/x.md"}},
+			map[string]string{"../a/x.md": "a/x.md", "../b/x.md": "b/x.md"}},
+		{"absolute path", []Item{{ID: "/tmp/docs/x.md", Path: "/tmp/docs/x.md"}}, map[string]string{"/tmp/docs/x.md": "tmp/docs/x.md"}},
+		{"collision gets a suffix",
+			[]Item{{ID: "x.md", Path: "x.md"}, {ID: "../x.md", Path: "../x.md"}, {ID: "/x.md", Path: "/x.md"}},
+			map[string]string{"x.md": "x.md", "../x.md": "x-2.md", "/x.md": "x-3.md"}},
+		{"collision ignores case",
+			[]Item{{ID: "X.md", Path: "X.md"}, {ID: "../x.md", Path: "../x.md"}},
+			map[string]string{"X.md": "X.md", "../x.md": "x-2.md"}},
+		{"record", []Item{{ID: "intro"}, {ID: "docs/1"}}, map[string]string{"intro": "intro.txt", "docs/1": "docs/1.txt"}},
+		{"invalid record id", []Item{{ID: "../x"}, {ID: "/x"}, {ID: "ok"}}, map[string]string{"ok": "ok.txt"}},
+		{"duplicate id", []Item{{ID: "a.md", Path: "a.md"}, {ID: "a.md", Path: "a.md"}}, map[string]string{"a.md": "a.md"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := OutputPaths("out", tt.items)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for id, want := range tt.want {
+				if want = filepath.Join("out", filepath.FromSlash(want)); got[id] != want {
+					t.Errorf("%s: got %q, want %q", id, got[id], want)
+				}
+			}
+		})
+	}
+}
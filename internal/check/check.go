@@ -0,0 +1,118 @@
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Issue is a problem reported by the model, validated against the checked text
+type Issue struct {
+	Start       int    `json:"start"` // byte offsets into the checked text
+	End         int    `json:"end"`
+	Text        string `json:"text"`
+	Category    string `json:"category"`
+	Explanation string `json:"explanation"`
+	Suggestion  string `json:"suggestion"`
+}
+
+// Parse reads the JSON array of issues from a model reply, ignoring any text or code fence around it
+func Parse(reply string) ([]Issue, error) {
+	start := strings.Index(reply, "[")
+	end := strings.LastIndex(reply, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no issue list in response")
+	}
+
+	var issues []Issue
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &issues); err != nil {
+		return nil, fmt.Errorf("invalid issue list: %w", err)
+	}
+	return issues, nil
+}
+
+// Validate anchors every issue on its quoted text. Models count offsets in characters and
+// often get them slightly wrong, so the occurrence nearest to the reported offset is used.
+// Issues whose text does not appear in the input are dropped.
+func Validate(input string, issues []Issue) (valid []Issue, dropped int) {
+	for _, issue := range issues {
+		if issue.Text == "" {
+			dropped++
+			continue
+		}
+		start := nearest(input, issue.Text, runeToByte(input, issue.Start))
+		if start < 0 {
+			dropped++
+			continue
+		}
+		issue.Start = start
+		issue.End = start + len(issue.Text)
+		if issue.Category == "" {
+			issue.Category = "issue"
+		}
+		valid = append(valid, issue)
+	}
+	return valid, dropped
+}
+
+// nearest returns the byte offset of the occurrence of text closest to offset, or -1
+func nearest(input, text string, offset int) int {
+	best := -1
+	for i := 0; ; {
+		idx := strings.Index(input[i:], text)
+		if idx < 0 {
+			break
+		}
+		pos := i + idx
+		if best < 0 || abs(pos-offset) < abs(best-offset) {
+			best = pos
+		}
+		i = pos + 1
+	}
+	return best
+}
+
+func runeToByte(s string, runes int) int {
+	if runes <= 0 {
+		return 0
+	}
+	for i := range s {
+		if runes == 0 {
+			return i
+		}
+		runes--
+	}
+	return len(s)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Format prints issues like compiler diagnostics: name:line:col: category: explanation
+func Format(name, input string, issues []Issue) string {
+	var sb strings.Builder
+	for _, issue := range issues {
+		line, col := position(input, issue.Start)
+		fmt.Fprintf(&sb, "%s:%d:%d: %s: %s", name, line, col, issue.Category, issue.Explanation)
+		if issue.Suggestion != "" {
+			fmt.Fprintf(&sb, " (%q -> %q)", issue.Text, issue.Suggestion)
+		} else {
+			fmt.Fprintf(&sb, " (%q)", issue.Text)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// position converts a byte offset to a 1-based line and column, the column counts characters
+func position(input string, offset int) (int, int) {
+	before := input[:offset]
+	line := strings.Count(before, "\n") + 1
+	lineStart := strings.LastIndex(before, "\n") + 1
+	return line, utf8.RuneCountInString(before[lineStart:]) + 1
+}
@@ -0,0 +1,76 @@
+package check
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   string
+		want    int
+		wantErr bool
+	}{
+		{"array", `[{"start":0,"end":3,"text":"teh"}]`, 1, false},
+		{"fenced", "```json\n[{\"text\":\"a\"},{\"text\":\"b\"}]\n```", 2, false},
+		{"empty", "[]", 0, false},
+		{"no array", "No issues found.", 0, true},
+		{"invalid", "[{]", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues, err := Parse(tt.reply)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(issues) != tt.want {
+				t.Errorf("got %d issues, want %d", len(issues), tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	input := "Thé cat is teh best. I like teh dog."
+	tests := []struct {
+		name      string
+		issue     Issue
+		wantStart int
+		dropped   bool
+	}{
+		{"offset in characters", Issue{Start: 11, Text: "teh"}, 12, false}, // é is two bytes
+		{"second occurrence", Issue{Start: 28, Text: "teh"}, 29, false},
+		{"offset slightly off", Issue{Start: 25, Text: "teh"}, 29, false},
+		{"wrong offset", Issue{Start: 0, Text: "teh"}, 12, false},
+		{"text not in input", Issue{Start: 4, Text: "dgo"}, 0, true},
+		{"no text", Issue{Start: 4}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid, dropped := Validate(input, []Issue{tt.issue})
+			if tt.dropped {
+				if dropped != 1 || len(valid) != 0 {
+					t.Errorf("got %v (%d dropped), want the issue dropped", valid, dropped)
+				}
+				return
+			}
+			if len(valid) != 1 {
+				t.Fatalf("issue dropped")
+			}
+			got := valid[0]
+			if got.Start != tt.wantStart || got.End != tt.wantStart+len(tt.issue.Text) || input[got.Start:got.End] != tt.issue.Text {
+				t.Errorf("span %d-%d, want %d", got.Start, got.End, tt.wantStart)
+			}
+			if got.Category != "issue" {
+				t.Errorf("category %q, want the default", got.Category)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	input := "First line.\nÀ teh end."
+	issues, _ := Validate(input, []Issue{{Text: "teh", Category: "spelling", Explanation: "typo", Suggestion: "the"}})
+	want := "notes.txt:2:3: spelling: typo (\"teh\" -> \"the\")\n"
+	if got := Format("notes.txt", input, issues); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Confirm asks a yes/no question on stderr and reads the answer from the terminal,
+// so it works even when stdin is a pipe. Anything other than y/yes is a no.
+func Confirm(question string) (bool, error) {
+	answer, err := Ask(question + " [y/N]: ")
+	if err != nil {
+		return false, err
+	}
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes", nil
+}
+
+// Ask prints a prompt on stderr and returns the line typed on the terminal
+func Ask(prompt string) (string, error) {
+	tty, err := openTerminal()
+	if err != nil {
+		return "", fmt.Errorf("cannot ask for confirmation: %w", err)
+	}
+	if tty != os.Stdin {
+		defer tty.Close()
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	line, err := bufio.NewReader(tty).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("cannot read answer: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func openTerminal() (*os.File, error) {
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		return os.Stdin, nil
+	}
+	name := "/dev/tty"
+	if os.PathSeparator == '\\' {
+		name = "CONIN$"
+	}
+	return os.Open(name)
+}
@@ -1,18 +1,31 @@
 package cli
 
 import (
+	"ai/internal/extract"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
-func ReadFile(path string, sizeLimitKB int) (string, error) {
+// ErrBinary is returned for files that are neither text nor a supported document
+var ErrBinary = errors.New("incorrect file type")
+
+// ReadFile reads a plaintext file, or extracts the text of a supported document
+// (PDF, DOCX, ODT, XLSX, and HTML with htmlText). Documents may be up to documentLimitKB
+// on disk, the extracted text is held to sizeLimitKB like any plaintext input.
+func ReadFile(path string, sizeLimitKB int, documentLimitKB int, htmlText bool) (string, error) {
 	info, err := os.Stat(path)
 	if err != nil {
 		return "", fmt.Errorf("failed to read file: %w", err)
 	}
 
-	if info.Size() > int64(sizeLimitKB*1024) {
+	if info.Size() > int64(max(sizeLimitKB, documentLimitKB)*1024) {
+		if binaryHead(path) {
+			return "", ErrBinary
+		}
 		return "", fmt.Errorf("file too large (%d bytes)", info.Size())
 	}
 
@@ -21,14 +34,81 @@ func ReadFile(path string, sizeLimitKB int) (string, error) {
 		return "", fmt.Errorf("failed to read file: %w", err)
 	}
 
+	text, kind, err := extract.Text(path, file, int64(max(sizeLimitKB, documentLimitKB))*1024, htmlText)
+	if err != nil {
+		return "", err
+	}
+
+	if len(text) > sizeLimitKB*1024 {
+		if kind == extract.Plain {
+			return "", fmt.Errorf("file too large (%d bytes)", len(text))
+		}
+		return "", fmt.Errorf("extracted %s text too large (%d bytes)", kind, len(text))
+	}
+
 	// Check nul bytes, verify accidental executable not passed
-	for _, b := range file {
-		if b == 0 {
-			return "", fmt.Errorf("incorrect file type")
+	for i := 0; i < len(text); i++ {
+		if text[i] == 0 {
+			return "", ErrBinary
+		}
+	}
+
+	return text, nil
+}
+
+// binaryHead reports whether the file starts like a binary file, without reading it whole
+func binaryHead(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, 8000)
+	n, _ := io.ReadFull(f, head)
+	return extract.Binary(head[:n])
+}
+
+// ReadFiles reads every file matched by the patterns. With more than one file, each one
+// is wrapped in a block labeled with its relative path. totalLimitKB caps the combined text.
+// Binary files found in a directory or by a glob are skipped and returned, a binary file
+// named as is fails.
+func ReadFiles(patterns []string, sizeLimitKB int, documentLimitKB int, totalLimitKB int, htmlText bool) (string, []string, error) {
+	files, err := ExpandFiles(patterns)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var texts, names, skipped []string
+	for _, name := range files {
+		text, err := ReadFile(name, sizeLimitKB, documentLimitKB, htmlText)
+		if errors.Is(err, ErrBinary) && !Named(patterns, name) {
+			skipped = append(skipped, name)
+			continue
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("%s: %w", name, err)
+		}
+		texts, names = append(texts, text), append(names, name)
+	}
+	if len(texts) == 0 {
+		return "", skipped, fmt.Errorf("no text files in %s", strings.Join(patterns, ", "))
+	}
+
+	var sb strings.Builder
+	for i, text := range texts {
+		if len(texts) == 1 {
+			sb.WriteString(text)
+		} else {
+			rel := RelPath(names[i])
+			fmt.Fprintf(&sb, "--- BEGIN FILE: %s ---\n%s\n--- END FILE: %s ---\n", rel, strings.TrimRight(text, "\n"), rel)
+		}
+
+		if sb.Len() > totalLimitKB*1024 {
+			return "", skipped, fmt.Errorf("combined input too large (limit %dKB)", totalLimitKB)
 		}
 	}
 
-	return string(file), nil
+	return sb.String(), skipped, nil
 }
 
 func WriteFile(name string, data []byte) error {
@@ -1,17 +1,121 @@
 package cli
 
-import "flag"
+import (
+	"flag"
+	"os"
+	"strings"
+)
+
+// Subcommands, given as the first argument (e.g. ai batch ...)
+var commands = map[string]bool{
+	"batch":            true,
+	"commit-msg":       true,
+	"review":           true,
+	"sh":               true,
+	"translate-locale": true,
+}
+
+// optionalString is a flag given alone (--extract-code) or with a value (--extract-code=go)
+type optionalString struct {
+	set   bool
+	value string
+}
+
+func (o *optionalString) String() string {
+	return o.value
+}
+
+func (o *optionalString) Set(value string) error {
+	o.set = true
+	if value != "true" {
+		o.value = value
+	}
+	return nil
+}
+
+func (o *optionalString) IsBoolFlag() bool {
+	return true
+}
+
+// stringList collects the values of a repeatable flag
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
 
 type CMDFlags struct {
 	IsRewrite   bool
 	IsTranslate bool
 	IsSummarize bool
+	IsCheck     bool
+	IsPatch     bool
+	IsExplain   bool
+	IsAgent     bool
+	Sources     bool
 	IsClipboard bool
 	Provider    string
 	Input       string
 	Language    string
-	File        string
+	From        string
+	Glossary    string
+	Files       []string
 	ToFile      string
+	Images      []string
+	Markdown    bool
+	HTMLText    bool
+	Raw         bool
+	Output      string
+
+	// Code extraction
+	ExtractCode bool
+	ExtractLang string
+	ExtractTo   string
+
+	// Rewrite presets
+	Tone   string
+	Style  string
+	Length string
+
+	// Summary presets
+	Format    string
+	Words     int
+	Sentences int
+
+	// Rewrite diff and in-place editing
+	Diff     bool
+	DiffMode string
+	InPlace  bool
+	Backup   bool
+	Yes      bool
+
+	// Compare mode
+	Compare     string
+	CompareView string
+	Judge       string
+	Rationale   bool
+
+	// Subcommand and its positional arguments
+	Command string
+	Args    []string
+
+	// Commit message mode
+	Commit bool
+	Edit   bool
+
+	// Shell command mode
+	FollowUp bool
+
+	// Batch mode
+	Workers int
+	OutDir  string
+	Results string
+	Resume  bool
 }
 
 func SetFlags() *CMDFlags {
@@ -20,12 +124,34 @@ func SetFlags() *CMDFlags {
 	var rewrite, r bool
 	var translate, t bool
 	var summarize, s bool
+	var check, patch bool
+	var explain, sources bool
+	var agent bool
 	var copyClipboard, c bool
 	var provider, p string
 	var input, i string
 	var language, l string
-	var file, f string
+	var from, glossary string
+	var files stringList
 	var toFile, tf string
+	var images stringList
+	var markdown, htmlText, raw bool
+	var output string
+	var extractCode optionalString
+	var extractTo string
+	var workers, w int
+	var outDir, results string
+	var resume bool
+	var commit, edit bool
+	var followUp bool
+	var tone, style, length string
+	var format string
+	var words, sentences int
+	var diff, inPlace, backup, yes bool
+	var diffMode string
+	var compare, compareView string
+	var judge string
+	var rationale bool
 
 	flag.BoolVar(&rewrite, "rewrite", false, "AI rewrite function flag")
 	flag.BoolVar(&r, "r", false, "AI rewrite function flag (shorthand)")
@@ -36,6 +162,14 @@ func SetFlags() *CMDFlags {
 	flag.BoolVar(&summarize, "summarize", false, "AI summarize function flag")
 	flag.BoolVar(&s, "s", false, "AI summarize function flag (shorthand)")
 
+	flag.BoolVar(&check, "check", false, "AI grammar check, report issues as diagnostics instead of rewriting")
+	flag.BoolVar(&patch, "patch", false, "Ask for a unified diff of the --file inputs and apply it after confirmation")
+
+	flag.BoolVar(&explain, "explain", false, "Explain piped errors, stack traces and logs: root cause and fix")
+	flag.BoolVar(&sources, "sources", false, "With --explain, include the project source lines referenced by the output")
+
+	flag.BoolVar(&agent, "agent", false, "Let the model read files, list directories, grep and run allowlisted commands, each call approved")
+
 	flag.BoolVar(&copyClipboard, "clipboard", false, "Copy result to clipboard automatically")
 	flag.BoolVar(&c, "c", false, "Copy result to clipboard automatically (shorthand)")
 
@@ -45,16 +179,86 @@ func SetFlags() *CMDFlags {
 	flag.StringVar(&input, "input", "", "AI prompt")
 	flag.StringVar(&i, "i", "", "AI prompt (shorthand)")
 
-	flag.StringVar(&language, "language", "", "Translation target language")
-	flag.StringVar(&l, "l", "", "Translation target language (shorthand)")
+	flag.StringVar(&language, "language", "", "Translation target language(s), comma separated")
+	flag.StringVar(&l, "l", "", "Translation target language(s) (shorthand)")
 
-	flag.StringVar(&file, "file", "", "Use file as input")
-	flag.StringVar(&f, "f", "", "Use file as input (shorthand)")
+	flag.StringVar(&from, "from", "", "Translation source language (detected when not set)")
+	flag.StringVar(&glossary, "glossary", "", "Translation glossary file (YAML or CSV)")
+
+	flag.Var(&files, "file", "Use file, directory or glob (e.g. 'src/**/*.go') as input, can be repeated")
+	flag.Var(&files, "f", "Use file, directory or glob as input (shorthand)")
 
 	flag.StringVar(&toFile, "tofile", "", "Output to a file")
 	flag.StringVar(&tf, "tf", "", "Output to a file (shorthand)")
 
-	flag.Parse()
+	flag.Var(&images, "image", "Attach an image (PNG/JPEG/WebP/GIF), can be repeated")
+	flag.Var(&images, "img", "Attach an image (shorthand)")
+
+	flag.BoolVar(&markdown, "markdown", false, "Rewrite or translate only the prose of Markdown input (automatic for .md files)")
+
+	flag.BoolVar(&htmlText, "html-text", false, "Send .html files as their extracted text instead of the markup (always with --summarize)")
+
+	flag.BoolVar(&raw, "raw", false, "Print the answer as is, without terminal Markdown rendering")
+
+	flag.StringVar(&output, "output", "text", "Output format: text, json (envelope with usage and warnings), jsonl (batch) or sarif (review)")
+	flag.StringVar(&output, "o", "text", "Output format (shorthand)")
+
+	flag.Var(&extractCode, "extract-code", "Print only the fenced code blocks of the answer, --extract-code=<lang> for one language")
+	flag.StringVar(&extractTo, "extract-to", "", "Write the code blocks of the answer to files in this directory")
+
+	flag.StringVar(&tone, "tone", "", "Rewrite tone preset (see rewrite.tones in config.yaml)")
+	flag.StringVar(&style, "style", "", "Rewrite style preset (see rewrite.styles in config.yaml)")
+	flag.StringVar(&length, "length", "", "Rewrite length: shorter, same or longer")
+
+	flag.StringVar(&format, "format", "", "Summary format: bullets, paragraph, tldr, outline or action-items")
+	flag.IntVar(&words, "words", 0, "Summary target length in words")
+	flag.IntVar(&sentences, "sentences", 0, "Summary target length in sentences")
+
+	flag.BoolVar(&diff, "diff", false, "Show a colored diff between the input and the rewrite")
+	flag.StringVar(&diffMode, "diff-mode", "word", "Diff granularity: word or line")
+	flag.BoolVar(&inPlace, "in-place", false, "Write the rewrite back into the --file source after confirmation")
+	flag.BoolVar(&backup, "backup", false, "With --in-place, keep the original as <file>.bak")
+	flag.BoolVar(&yes, "yes", false, "Do not ask for confirmation")
+	flag.BoolVar(&yes, "y", false, "Do not ask for confirmation (shorthand)")
+
+	flag.StringVar(&compare, "compare", "", "Run the prompt on several providers (e.g. ollama,claude,gemini)")
+	flag.StringVar(&compareView, "compare-view", "sections", "Compare output: sections, side or json")
+
+	flag.StringVar(&judge, "judge", "", "Provider that picks or merges the best --compare answer")
+	flag.BoolVar(&rationale, "rationale", false, "Print the judge rationale to stderr")
+
+	flag.IntVar(&workers, "workers", 0, "Batch: number of concurrent workers")
+	flag.IntVar(&w, "w", 0, "Batch: number of concurrent workers (shorthand)")
+
+	flag.StringVar(&outDir, "out-dir", "", "Batch: write each output to a mirrored path under this directory")
+	flag.StringVar(&results, "results", "", "Batch: write results to a JSONL file")
+	flag.BoolVar(&resume, "resume", false, "Batch: skip inputs completed by a previous run")
+
+	flag.BoolVar(&commit, "commit", false, "Commit-msg: run git commit with the generated message after confirmation")
+	flag.BoolVar(&edit, "edit", false, "Commit-msg: review the generated message in the git editor before committing")
+
+	flag.BoolVar(&followUp, "follow-up", false, "Sh: send the command output back with a follow-up request")
+
+	args := os.Args[1:]
+	if len(args) > 0 && commands[args[0]] {
+		flags.Command = args[0]
+		args = args[1:]
+	}
+
+	// Allow flags and positional arguments to be mixed (ai batch docs/*.md -t -l German)
+	for {
+		_ = flag.CommandLine.Parse(args) // exits on error
+		rest := flag.Args()
+		if len(rest) == 0 {
+			break
+		}
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			flags.Args = append(flags.Args, rest...)
+			break
+		}
+		flags.Args = append(flags.Args, rest[0])
+		args = rest[1:]
+	}
 
 	firstNonEmpty := func(a, b string) string {
 		if a != "" {
@@ -66,12 +270,49 @@ func SetFlags() *CMDFlags {
 	flags.IsRewrite = rewrite || r
 	flags.IsTranslate = translate || t
 	flags.IsSummarize = summarize || s
+	flags.IsCheck = check
+	flags.IsPatch = patch
+	flags.IsExplain = explain
+	flags.Sources = sources
+	flags.IsAgent = agent
 	flags.IsClipboard = copyClipboard || c
 	flags.Provider = firstNonEmpty(provider, p)
 	flags.Input = firstNonEmpty(input, i)
 	flags.Language = firstNonEmpty(language, l)
-	flags.File = firstNonEmpty(file, f)
+	flags.From = from
+	flags.Glossary = glossary
+	flags.Files = files
 	flags.ToFile = firstNonEmpty(toFile, tf)
+	flags.Images = images
+	flags.Markdown = markdown
+	flags.HTMLText = htmlText
+	flags.Raw = raw
+	flags.Output = output
+	flags.ExtractCode = extractCode.set
+	flags.ExtractLang = extractCode.value
+	flags.ExtractTo = extractTo
+	flags.Tone = tone
+	flags.Style = style
+	flags.Length = length
+	flags.Format = format
+	flags.Words = words
+	flags.Sentences = sentences
+	flags.Diff = diff
+	flags.DiffMode = diffMode
+	flags.InPlace = inPlace
+	flags.Backup = backup
+	flags.Yes = yes
+	flags.Compare = compare
+	flags.CompareView = compareView
+	flags.Judge = judge
+	flags.Rationale = rationale
+	flags.Workers = max(workers, w)
+	flags.OutDir = outDir
+	flags.Results = results
+	flags.Resume = resume
+	flags.Commit = commit || edit
+	flags.Edit = edit
+	flags.FollowUp = followUp
 
 	return flags
 }
@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ExpandFiles resolves file, directory and glob patterns (including **) into a list of files.
+// Explicitly named files are always kept, files found by walking a directory or glob
+// are filtered through the .gitignore/.aiignore rules of the current directory.
+func ExpandFiles(patterns []string) ([]string, error) {
+	ignore, err := LoadIgnore(".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ignore file: %w", err)
+	}
+
+	var files []string
+	seen := map[string]bool{}
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			files = append(files, name)
+		}
+	}
+
+	for _, pattern := range patterns {
+		if !hasGlobMeta(pattern) {
+			info, err := os.Stat(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read file: %w", err)
+			}
+			if !info.IsDir() {
+				add(pattern)
+				continue
+			}
+			// A directory matches everything below it
+			pattern = path.Join(filepath.ToSlash(pattern), "**")
+		}
+
+		matches, err := walkGlob(filepath.ToSlash(pattern), ignore)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %q", pattern)
+		}
+		for _, m := range matches {
+			add(m)
+		}
+	}
+	return files, nil
+}
+
+// Named reports whether the file was named as is in the patterns, not found by walking a
+// directory or matching a glob
+func Named(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if p == name && !hasGlobMeta(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasGlobMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// walkGlob walks the static prefix of the pattern and collects the files matching it
+func walkGlob(pattern string, ignore *IgnoreList) ([]string, error) {
+	root := "."
+	segments := strings.Split(pattern, "/")
+	for i, seg := range segments {
+		if hasGlobMeta(seg) {
+			if i > 0 {
+				root = path.Join(segments[:i]...)
+				if strings.HasPrefix(pattern, "/") {
+					root = "/" + root
+				}
+			}
+			break
+		}
+	}
+
+	var matches []string
+	err := filepath.WalkDir(root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		slashed := filepath.ToSlash(name)
+		rel := RelPath(name)
+		if d.IsDir() {
+			if name != root && (d.Name() == ".git" || ignore.Ignored(rel, true)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ignore.Ignored(rel, false) {
+			return nil
+		}
+		if matchGlob(strings.TrimPrefix(pattern, "./"), strings.TrimPrefix(slashed, "./")) {
+			matches = append(matches, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand %q: %w", pattern, err)
+	}
+	return matches, nil
+}
+
+// matchGlob matches a slash separated path against a pattern where ** spans any number of directories
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// RelPath returns a slash separated path relative to the current directory when possible
+func RelPath(name string) string {
+	if abs, err := filepath.Abs(name); err == nil {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, abs); err == nil {
+				return filepath.ToSlash(rel)
+			}
+		}
+	}
+	return filepath.ToSlash(name)
+}
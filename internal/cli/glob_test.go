@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/main.go", false},
+		{"src/*.go", "src/a.go", true},
+		{"src/*.go", "src/sub/a.go", false},
+		{"src/**/*.go", "src/a.go", true},
+		{"src/**/*.go", "src/sub/deep/a.go", true},
+		{"src/**/*.go", "other/a.go", false},
+		{"**", "a/b/c", true},
+		{"**/test", "a/b/test", true},
+		{"**/test", "a/b/test/x", false},
+		{"docs/**", "docs/a/b.md", true},
+		{"a?c.txt", "abc.txt", true},
+		{"[ab].txt", "c.txt", false},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIgnored(t *testing.T) {
+	dir := t.TempDir()
+	rules := "# comment\n*.log\n!keep.log\nbuild/\n/docs/internal\nvendor/**/*.go\n"
+	if err := os.WriteFile(filepath.Join(dir, ".gitignore"), []byte(rules), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".aiignore"), []byte("secret.txt\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	list, err := LoadIgnore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{"app.log", false, true},
+		{"logs/app.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"build", false, false}, // a file named like the directory rule
+		{"src/build", true, true},
+		{"docs/internal", true, true},
+		{"src/docs/internal", true, false}, // anchored rules match from the root
+		{"vendor/x/y.go", false, true},
+		{"vendor/x/y.txt", false, false},
+		{"secret.txt", false, true},
+		{"main.go", false, false},
+	}
+	for _, tt := range tests {
+		if got := list.Ignored(tt.rel, tt.isDir); got != tt.want {
+			t.Errorf("Ignored(%q, %v) = %v, want %v", tt.rel, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestExpandFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.go", "b.txt", "sub/c.go", "sub/deep/d.go", "gen/e.go", ".git/f.go"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("gen/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+
+	tests := []struct {
+		patterns []string
+		want     []string
+	}{
+		{[]string{"a.go"}, []string{"a.go"}},
+		{[]string{"*.go"}, []string{"a.go"}},
+		{[]string{"**/*.go"}, []string{"a.go", "sub/c.go", "sub/deep/d.go"}},
+		{[]string{"sub"}, []string{"sub/c.go", "sub/deep/d.go"}},
+		{[]string{"gen/e.go"}, []string{"gen/e.go"}}, // named files are kept even when ignored
+		{[]string{"a.go", "*.go"}, []string{"a.go"}},
+	}
+	for _, tt := range tests {
+		got, err := ExpandFiles(tt.patterns)
+		if err != nil {
+			t.Errorf("ExpandFiles(%q): %v", tt.patterns, err)
+			continue
+		}
+		for i := range got {
+			got[i] = filepath.ToSlash(got[i])
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("ExpandFiles(%q) = %q, want %q", tt.patterns, got, tt.want)
+		}
+	}
+
+	if _, err := ExpandFiles([]string{"*.md"}); err == nil {
+		t.Error("ExpandFiles of a pattern matching nothing should fail")
+	}
+}
+
+func TestReadFilesSkipsBinary(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.MkdirAll("src", 0755)
+	os.WriteFile("src/a.txt", []byte("alpha\n"), 0644)
+	os.WriteFile("src/b.txt", []byte("beta\n"), 0644)
+	os.WriteFile("src/tool.bin", []byte("\x7fELF\x00\x01"), 0644)
+
+	tests := []struct {
+		patterns []string
+		skipped  []string
+		wantErr  bool
+	}{
+		{[]string{"src"}, []string{filepath.Join("src", "tool.bin")}, false},
+		{[]string{"src/*"}, []string{"src/tool.bin"}, false},
+		{[]string{"src/a.txt", "src/tool.bin"}, nil, true},
+		{[]string{"src/*.bin"}, []string{"src/tool.bin"}, true},
+	}
+	for _, tt := range tests {
+		text, skipped, err := ReadFiles(tt.patterns, 64, 1024, 256, false)
+		if (err != nil) != tt.wantErr || !slices.Equal(skipped, tt.skipped) {
+			t.Errorf("ReadFiles(%q) skipped %q, err %v", tt.patterns, skipped, err)
+		}
+		if err == nil && text != "--- BEGIN FILE: src/a.txt ---\nalpha\n--- END FILE: src/a.txt ---\n--- BEGIN FILE: src/b.txt ---\nbeta\n--- END FILE: src/b.txt ---\n" {
+			t.Errorf("ReadFiles(%q) = %q", tt.patterns, text)
+		}
+	}
+}
+
+func TestReadFileLargeBinary(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.WriteFile("big.bin", append([]byte("\x7fELF\x00"), make([]byte, 100*1024)...), 0644)
+	os.WriteFile("big.txt", []byte(strings.Repeat("text\n", 20*1024)), 0644)
+	if _, err := ReadFile("big.bin", 64, 64, false); !errors.Is(err, ErrBinary) {
+		t.Errorf("large binary file: %v, want ErrBinary", err)
+	}
+	if _, err := ReadFile("big.txt", 64, 64, false); err == nil || errors.Is(err, ErrBinary) {
+		t.Errorf("large text file: %v, want too large", err)
+	}
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreRule is a single line of a .gitignore/.aiignore file
+type ignoreRule struct {
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool // pattern contains a slash, match against the full relative path
+}
+
+// IgnoreList holds the rules of the .gitignore and .aiignore files in a directory
+type IgnoreList struct {
+	rules []ignoreRule
+}
+
+// LoadIgnore reads .gitignore and .aiignore from dir, missing files are skipped
+func LoadIgnore(dir string) (*IgnoreList, error) {
+	list := &IgnoreList{}
+	for _, name := range []string{".gitignore", ".aiignore"} {
+		file, err := os.Open(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimRight(scanner.Text(), " \t\r")
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			rule := ignoreRule{}
+			if strings.HasPrefix(line, "!") {
+				rule.negate = true
+				line = line[1:]
+			}
+			if strings.HasSuffix(line, "/") {
+				rule.dirOnly = true
+				line = strings.TrimSuffix(line, "/")
+			}
+			if strings.Contains(line, "/") {
+				rule.anchored = true
+				line = strings.TrimPrefix(line, "/")
+			}
+			rule.pattern = line
+			list.rules = append(list.rules, rule)
+		}
+		file.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// Ignored reports whether a slash separated path relative to the ignore file directory is excluded.
+// Like git, the last matching rule wins.
+func (l *IgnoreList) Ignored(rel string, isDir bool) bool {
+	if l == nil {
+		return false
+	}
+	ignored := false
+	for _, rule := range l.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		var match bool
+		if rule.anchored {
+			match = matchGlob(rule.pattern, rel)
+		} else {
+			match, _ = path.Match(rule.pattern, path.Base(rel))
+		}
+		if match {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// Supported image types for vision-capable models
+var imageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/webp": true,
+	"image/gif":  true,
+}
+
+// ReadImage reads an image file and returns its mime type and raw bytes
+func ReadImage(path string, sizeLimitKB int) (string, []byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	if info.Size() > int64(sizeLimitKB*1024) {
+		return "", nil, fmt.Errorf("image too large (%d bytes)", info.Size())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	mimeType := http.DetectContentType(data)
+	if !imageTypes[mimeType] {
+		return "", nil, fmt.Errorf("unsupported image type %q (%s)", mimeType, path)
+	}
+
+	return mimeType, data, nil
+}
@@ -0,0 +1,19 @@
+package cli
+
+import (
+	"os"
+)
+
+// IsTerminal reports whether f is a terminal rather than a pipe or a file
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// UseColor reports whether ANSI colors may be written to stdout (https://no-color.org)
+func UseColor() bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return IsTerminal(os.Stdout)
+}
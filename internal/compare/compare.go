@@ -0,0 +1,141 @@
+package compare
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Result is the answer of one provider for the compared prompt
+type Result struct {
+	Provider     string        `json:"provider"`
+	Model        string        `json:"model,omitempty"`
+	Output       string        `json:"output,omitempty"`
+	Error        string        `json:"error,omitempty"`
+	Latency      time.Duration `json:"-"`
+	LatencyMs    int64         `json:"latencyMs"`
+	InputTokens  int           `json:"inputTokens"`
+	OutputTokens int           `json:"outputTokens"`
+}
+
+const (
+	ViewSections = "sections"
+	ViewSide     = "side"
+	ViewJSON     = "json"
+)
+
+// Render formats the results as labeled sections, side by side columns fitting width, or JSON
+func Render(results []Result, view string, width int) (string, error) {
+	switch view {
+	case "", ViewSections:
+		return renderSections(results), nil
+	case ViewSide:
+		return renderSide(results, width), nil
+	case ViewJSON:
+		for i := range results {
+			results[i].LatencyMs = results[i].Latency.Milliseconds()
+		}
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+	return "", fmt.Errorf("unknown compare view %q (sections, side, json)", view)
+}
+
+func header(r Result) string {
+	if r.Model == "" {
+		return r.Provider
+	}
+	return r.Provider + " (" + r.Model + ")"
+}
+
+func stats(r Result) string {
+	return fmt.Sprintf("%s, %d in / %d out tokens", r.Latency.Round(time.Millisecond), r.InputTokens, r.OutputTokens)
+}
+
+func body(r Result) string {
+	if r.Error != "" {
+		return "Error: " + r.Error
+	}
+	return strings.TrimSpace(r.Output)
+}
+
+func renderSections(results []Result) string {
+	var sb strings.Builder
+	for i, r := range results {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		fmt.Fprintf(&sb, "=== %s · %s ===\n%s", header(r), stats(r), body(r))
+	}
+	return sb.String()
+}
+
+func renderSide(results []Result, width int) string {
+	const sep = " │ "
+	n := len(results)
+	colWidth := (width - (n-1)*utf8.RuneCountInString(sep)) / n
+	if colWidth < 10 {
+		// Too narrow for columns
+		return renderSections(results)
+	}
+
+	columns := make([][]string, n)
+	height := 0
+	for i, r := range results {
+		lines := wrap(header(r), colWidth)
+		lines = append(lines, wrap(stats(r), colWidth)...)
+		lines = append(lines, strings.Repeat("─", colWidth))
+		lines = append(lines, wrap(body(r), colWidth)...)
+		columns[i] = lines
+		height = max(height, len(lines))
+	}
+
+	var sb strings.Builder
+	for row := 0; row < height; row++ {
+		cells := make([]string, n)
+		for i, col := range columns {
+			cell := ""
+			if row < len(col) {
+				cell = col[row]
+			}
+			cells[i] = cell + strings.Repeat(" ", colWidth-utf8.RuneCountInString(cell))
+		}
+		sb.WriteString(strings.TrimRight(strings.Join(cells, sep), " ") + "\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// wrap breaks text into lines of at most width runes, splitting on spaces where possible
+func wrap(text string, width int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			for utf8.RuneCountInString(word) > width {
+				if line != "" {
+					lines = append(lines, line)
+					line = ""
+				}
+				runes := []rune(word)
+				lines = append(lines, string(runes[:width]))
+				word = string(runes[width:])
+			}
+			switch {
+			case line == "":
+				line = word
+			case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) <= width:
+				line += " " + word
+			default:
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
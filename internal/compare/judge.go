@@ -0,0 +1,49 @@
+package compare
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	rationaleMarker = "RATIONALE:"
+	answerMarker    = "ANSWER:"
+)
+
+// JudgePrompt asks the judge to pick or merge the best of the successful candidate answers
+func JudgePrompt(rubric, task string, results []Result) string {
+	var sb strings.Builder
+	sb.WriteString(rubric)
+	sb.WriteString("\n\nTask given to every candidate:\n")
+	sb.WriteString(task)
+	sb.WriteString("\n\n")
+
+	n := 0
+	for _, r := range results {
+		if r.Error != "" {
+			continue
+		}
+		n++
+		fmt.Fprintf(&sb, "--- Candidate %d (%s) ---\n%s\n\n", n, r.Provider, strings.TrimSpace(r.Output))
+	}
+
+	fmt.Fprintf(&sb, "Reply in exactly this format:\n%s <why the answer was chosen or how it was merged>\n%s <the final answer only>", rationaleMarker, answerMarker)
+	return sb.String()
+}
+
+// ParseVerdict splits the judge reply into the final answer and its rationale. The answer
+// starts at the first ANSWER: after RATIONALE:, so an answer quoting the marker is kept whole.
+// A reply without the expected markers is used as the answer as a whole.
+func ParseVerdict(reply string) (answer string, rationale string) {
+	start := 0
+	if r := strings.Index(reply, rationaleMarker); r >= 0 {
+		start = r + len(rationaleMarker)
+	}
+	idx := strings.Index(reply[start:], answerMarker)
+	if idx < 0 {
+		return strings.TrimSpace(reply), ""
+	}
+	answer = strings.TrimSpace(reply[start+idx+len(answerMarker):])
+	rationale = reply[start : start+idx]
+	return answer, strings.TrimSpace(rationale)
+}
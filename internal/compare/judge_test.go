@@ -0,0 +1,28 @@
+package compare
+
+import "testing"
+
+func TestParseVerdict(t *testing.T) {
+	tests := []struct {
+		name, reply, answer, rationale string
+	}{
+		{"markers", "RATIONALE: B is clearer\nANSWER: final text", "final text", "B is clearer"},
+		{"no markers", "just an answer", "just an answer", ""},
+		{"answer only", "ANSWER: only this", "only this", ""},
+		{
+			"answer quoting the marker",
+			"RATIONALE: merged both\nANSWER: Q: what?\nANSWER: this",
+			"Q: what?\nANSWER: this",
+			"merged both",
+		},
+		{"multiline", "RATIONALE:\nA and B\n\nANSWER:\nline 1\nline 2\n", "line 1\nline 2", "A and B"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			answer, rationale := ParseVerdict(tt.reply)
+			if answer != tt.answer || rationale != tt.rationale {
+				t.Errorf("got (%q, %q), want (%q, %q)", answer, rationale, tt.answer, tt.rationale)
+			}
+		})
+	}
+}
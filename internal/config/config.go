@@ -18,6 +18,66 @@ type Prompts struct {
 	Rewrite   string `yaml:"rewrite"`
 	Translate string `yaml:"translate"`
 	Summarize string `yaml:"summarize"`
+	Judge     string `yaml:"judge"`
+	Check     string `yaml:"check"`
+	Explain   string `yaml:"explain"`
+	Patch     string `yaml:"patch"`
+	CommitMsg string `yaml:"commitMsg"`
+	Review    string `yaml:"review"`
+	Shell     string `yaml:"shell"`
+	Agent     string `yaml:"agent"`
+
+	DetectLanguage string `yaml:"detectLanguage"`
+}
+
+// Rewrite holds the presets selectable with --tone, --style and --length
+type Rewrite struct {
+	Tones   map[string]string `yaml:"tones"`
+	Styles  map[string]string `yaml:"styles"`
+	Lengths map[string]string `yaml:"lengths"`
+}
+
+// Summarize holds the --format presets and the length target check settings
+type Summarize struct {
+	Formats          map[string]string `yaml:"formats"`
+	TolerancePercent int               `yaml:"tolerancePercent"` // accepted distance from --words/--sentences
+	MaxRetries       int               `yaml:"maxRetries"`
+}
+
+type Subtitles struct {
+	BatchCues int `yaml:"batchCues"` // cues translated per request
+}
+
+type Markdown struct {
+	BatchSegments int `yaml:"batchSegments"` // prose segments sent per request
+}
+
+type Locale struct {
+	BatchSize int `yaml:"batchSize"` // strings translated per request
+}
+
+// CommitMsg holds the ai commit-msg template and the files left out of the diff
+type CommitMsg struct {
+	Template string   `yaml:"template"`
+	Exclude  []string `yaml:"exclude"` // file name patterns, e.g. go.sum or *.lock
+}
+
+// Review holds the ai review request size and the files left out of the diff
+type Review struct {
+	ChunkKB int      `yaml:"chunkKB"` // annotated diff sent per request, 0 = inputFileLimitKB
+	Exclude []string `yaml:"exclude"`
+}
+
+// Shell holds the patterns of commands that ai sh only runs after they are typed back
+type Shell struct {
+	Denylist []string `yaml:"denylist"` // regular expressions
+}
+
+// Agent holds the limits of --agent and the commands its run_command tool may run
+type Agent struct {
+	MaxSteps              int      `yaml:"maxSteps"` // model turns before giving up
+	CommandTimeoutSeconds int      `yaml:"commandTimeoutSeconds"`
+	AllowedCommands       []string `yaml:"allowedCommands"` // command prefixes, e.g. "go test"
 }
 
 type BaseEndpoints struct {
@@ -36,14 +96,39 @@ type Openai struct {
 	Temperature float64 `yaml:"Temperature"`
 }
 
+type Batch struct {
+	Workers int `yaml:"workers"`
+}
+
+// RateLimit is a client-side limit for one provider, 0 = unlimited
+type RateLimit struct {
+	RequestsPerMinute int `yaml:"requestsPerMinute"`
+	TokensPerMinute   int `yaml:"tokensPerMinute"`
+}
+
 type Config struct {
-	HttpTimeoutSeconds int           `yaml:"httpTimeoutSeconds"`
-	Models             Models        `yaml:"models"`
-	Prompts            Prompts       `yaml:"prompts"`
-	BaseEndpoints      BaseEndpoints `yaml:"baseEndpoint"`
-	InputFileLimitKB   int           `yaml:"inputFileLimitKB"`
-	Claude             Claude        `yaml:"claude"`
-	Openai             Openai        `yaml:"openai"`
+	HttpTimeoutSeconds   int                  `yaml:"httpTimeoutSeconds"`
+	Models               Models               `yaml:"models"`
+	Prompts              Prompts              `yaml:"prompts"`
+	Rewrite              Rewrite              `yaml:"rewrite"`
+	Summarize            Summarize            `yaml:"summarize"`
+	Subtitles            Subtitles            `yaml:"subtitles"`
+	Locale               Locale               `yaml:"locale"`
+	Markdown             Markdown             `yaml:"markdown"`
+	CommitMsg            CommitMsg            `yaml:"commitMsg"`
+	Review               Review               `yaml:"review"`
+	Shell                Shell                `yaml:"shell"`
+	Agent                Agent                `yaml:"agent"`
+	BaseEndpoints        BaseEndpoints        `yaml:"baseEndpoint"`
+	InputFileLimitKB     int                  `yaml:"inputFileLimitKB"`
+	InputImageLimitKB    int                  `yaml:"inputImageLimitKB"`
+	InputDocumentLimitKB int                  `yaml:"inputDocumentLimitKB"`
+	InputTotalLimitKB    int                  `yaml:"inputTotalLimitKB"`
+	Claude               Claude               `yaml:"claude"`
+	Openai               Openai               `yaml:"openai"`
+	Batch                Batch                `yaml:"batch"`
+	RateLimits           map[string]RateLimit `yaml:"rateLimits"`
+	Glossary             string               `yaml:"glossary"` // default glossary file for translations
 }
 
 func Load() (*Config, error) {
@@ -69,10 +154,35 @@ func Load() (*Config, error) {
 		}
 	}
 
-	var cfg *Config
-	if err := yaml.Unmarshal(res, &cfg); err != nil {
+	return parse(res)
+}
+
+// Input limits used when config.yaml predates their key or sets it to 0
+const (
+	defaultInputFileLimitKB     = 128
+	defaultInputImageLimitKB    = 5120
+	defaultInputDocumentLimitKB = 10240
+	defaultInputTotalLimitKB    = 512
+)
+
+func parse(data []byte) (*Config, error) {
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, err
 	}
 
-	return cfg, nil
+	for _, limit := range []struct {
+		value *int
+		def   int
+	}{
+		{&cfg.InputFileLimitKB, defaultInputFileLimitKB},
+		{&cfg.InputImageLimitKB, defaultInputImageLimitKB},
+		{&cfg.InputDocumentLimitKB, defaultInputDocumentLimitKB},
+		{&cfg.InputTotalLimitKB, defaultInputTotalLimitKB},
+	} {
+		if *limit.value <= 0 {
+			*limit.value = limit.def
+		}
+	}
+	return &cfg, nil
 }
@@ -0,0 +1,28 @@
+package config
+
+import "testing"
+
+func TestInputLimitDefaults(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want [4]int // file, image, document, total
+	}{
+		{"missing keys", "httpTimeoutSeconds: 30\n", [4]int{128, 5120, 10240, 512}},
+		{"zero values", "inputFileLimitKB: 0\ninputImageLimitKB: 0\ninputDocumentLimitKB: 0\ninputTotalLimitKB: 0\n", [4]int{128, 5120, 10240, 512}},
+		{"set values", "inputFileLimitKB: 64\ninputImageLimitKB: 100\ninputDocumentLimitKB: 200\ninputTotalLimitKB: 300\n", [4]int{64, 100, 200, 300}},
+		{"empty file", "", [4]int{128, 5120, 10240, 512}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parse([]byte(tt.yaml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := [4]int{cfg.InputFileLimitKB, cfg.InputImageLimitKB, cfg.InputDocumentLimitKB, cfg.InputTotalLimitKB}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
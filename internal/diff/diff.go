@@ -0,0 +1,203 @@
+package diff
+
+import (
+	"strings"
+	"unicode"
+)
+
+type Op int
+
+const (
+	Equal Op = iota
+	Insert
+	Delete
+)
+
+// Edit is a run of tokens that are equal, inserted or deleted
+type Edit struct {
+	Op   Op
+	Text string
+}
+
+// maxSteps caps the edit distance searched, the trace takes O(D²) memory. Past it the
+// differing middle is reported as deleted and inserted as a whole.
+const maxSteps = 2000
+
+// Words diffs a and b word by word, each token keeps the whitespace that follows it. Texts
+// too different to diff by word are diffed by line.
+func Words(a, b string) []Edit {
+	edits, ok := diff(splitWords(a), splitWords(b))
+	if !ok {
+		return Lines(a, b)
+	}
+	return edits
+}
+
+// Lines diffs a and b line by line, each token keeps its newline
+func Lines(a, b string) []Edit {
+	edits, _ := diff(splitLines(a), splitLines(b))
+	return edits
+}
+
+func splitWords(s string) []string {
+	var tokens []string
+	start := 0
+	inSpace := true
+	for i, r := range s {
+		space := unicode.IsSpace(r)
+		if !space && inSpace && i > start {
+			// word boundary, the whitespace stays with the previous word
+			tokens = append(tokens, s[start:i])
+			start = i
+		}
+		inSpace = space
+	}
+	if start < len(s) {
+		tokens = append(tokens, s[start:])
+	}
+	return tokens
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diff computes the shortest edit script between a and b using Myers' algorithm, ok is false
+// when the edit distance is over maxSteps and the middle was replaced as a whole
+func diff(a, b []string) ([]Edit, bool) {
+	// Trim the common prefix and suffix, rewrites usually touch small parts of the text
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var edits []Edit
+	for _, t := range a[:prefix] {
+		edits = appendEdit(edits, Equal, t)
+	}
+	middle, ok := myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+	edits = append(edits, middle...)
+	for _, t := range a[len(a)-suffix:] {
+		edits = appendEdit(edits, Equal, t)
+	}
+	return merge(edits), ok
+}
+
+func myers(a, b []string) ([]Edit, bool) {
+	n, m := len(a), len(b)
+	maxD := n + m
+	if maxD == 0 {
+		return nil, true
+	}
+
+	offset := maxD
+	v := make([]int, 2*maxD+2)
+	// trace[d] keeps the diagonals -d-1..d+1 of v before step d, the only ones backtrack reads
+	var trace [][]int
+
+	for d := 0; d <= maxD; d++ {
+		if d > maxSteps {
+			return replace(a, b), false
+		}
+		lo, hi := max(offset-d-1, 0), min(offset+d+2, len(v))
+		trace = append(trace, append([]int(nil), v[lo:hi]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b, offset), true
+			}
+		}
+	}
+	return nil, true
+}
+
+// replace is the edit script deleting all of a and inserting all of b
+func replace(a, b []string) []Edit {
+	edits := make([]Edit, 0, len(a)+len(b))
+	for _, t := range a {
+		edits = append(edits, Edit{Delete, t})
+	}
+	for _, t := range b {
+		edits = append(edits, Edit{Insert, t})
+	}
+	return edits
+}
+
+func backtrack(trace [][]int, a, b []string, offset int) []Edit {
+	var edits []Edit
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		// index of diagonal k in the window kept for step d
+		at := func(k int) int { return offset + k - max(offset-d-1, 0) }
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[at(k-1)] < v[at(k+1)]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[at(prevK)]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, Edit{Equal, a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				y--
+				edits = append(edits, Edit{Insert, b[y]})
+			} else {
+				x--
+				edits = append(edits, Edit{Delete, a[x]})
+			}
+		}
+	}
+
+	// Built backwards
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+func appendEdit(edits []Edit, op Op, text string) []Edit {
+	return append(edits, Edit{op, text})
+}
+
+// merge joins consecutive edits of the same kind
+func merge(edits []Edit) []Edit {
+	var out []Edit
+	for _, e := range edits {
+		if len(out) > 0 && out[len(out)-1].Op == e.Op {
+			out[len(out)-1].Text += e.Text
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
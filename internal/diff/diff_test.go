@@ -0,0 +1,131 @@
+package diff
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// sides rebuilds the two texts an edit script was computed from
+func sides(edits []Edit) (string, string) {
+	var a, b strings.Builder
+	for _, e := range edits {
+		if e.Op != Insert {
+			a.WriteString(e.Text)
+		}
+		if e.Op != Delete {
+			b.WriteString(e.Text)
+		}
+	}
+	return a.String(), b.String()
+}
+
+// lcs is the length of the longest common subsequence, the tokens a minimal script keeps
+func lcs(a, b []string) int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				cur[j] = prev[j+1] + 1
+			} else {
+				cur[j] = max(prev[j], cur[j+1])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[0]
+}
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{"the cat sat", "the cat sat", "the cat sat"},
+		{"the cat sat", "the dog sat", "the [-cat-]{+dog+} sat"},
+		{"one two. ", "one two. three", "one two. {+three+}"},
+		{"one two three", "one three", "one [-two-] three"},
+		{"", "new text", "{+new text+}"},
+	}
+	for _, tt := range tests {
+		edits := Words(tt.a, tt.b)
+		if got := RenderWords(edits, false); got != tt.want {
+			t.Errorf("Words(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+		if a, b := sides(edits); a != tt.a || b != tt.b {
+			t.Errorf("Words(%q, %q) rebuilds %q, %q", tt.a, tt.b, a, b)
+		}
+	}
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{"a\nb\nc\n", "a\nc\n", " a\n-b\n c\n"},
+		{"a\nb\n", "a\nB\n", " a\n-b\n+B\n"},
+		{"a\n", "a\nb", " a\n+b\n"},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		if got := RenderLines(Lines(tt.a, tt.b), false); got != tt.want {
+			t.Errorf("Lines(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestLinesMinimal(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	text := func() string {
+		var sb strings.Builder
+		for n := r.Intn(20); n > 0; n-- {
+			fmt.Fprintf(&sb, "%c\n", 'a'+r.Intn(3))
+		}
+		return sb.String()
+	}
+	for i := 0; i < 500; i++ {
+		a, b := text(), text()
+		edits := Lines(a, b)
+		if gotA, gotB := sides(edits); gotA != a || gotB != b {
+			t.Fatalf("Lines(%q, %q) rebuilds %q, %q", a, b, gotA, gotB)
+		}
+		kept := 0
+		for _, e := range edits {
+			if e.Op == Equal {
+				kept += len(splitLines(e.Text))
+			}
+		}
+		if want := lcs(splitLines(a), splitLines(b)); kept != want {
+			t.Fatalf("Lines(%q, %q) keeps %d lines, want %d", a, b, kept, want)
+		}
+	}
+}
+
+func TestWordsLargeRewrite(t *testing.T) {
+	// Far past maxSteps: the word diff gives up and the texts are diffed by line
+	r := rand.New(rand.NewSource(2))
+	var a, b strings.Builder
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&a, "w%d ", r.Intn(50))
+		fmt.Fprintf(&b, "w%d ", r.Intn(50))
+		if i%10 == 9 {
+			a.WriteString("\n")
+			b.WriteString("\n")
+		}
+	}
+	edits := Words(a.String(), b.String())
+	if gotA, gotB := sides(edits); gotA != a.String() || gotB != b.String() {
+		t.Fatal("large rewrite does not rebuild its texts")
+	}
+}
+
+func TestChanged(t *testing.T) {
+	if Changed(Words("same text", "same text")) {
+		t.Error("identical texts reported as changed")
+	}
+	if !Changed(Words("same text", "other text")) {
+		t.Error("different texts reported as unchanged")
+	}
+}
@@ -0,0 +1,107 @@
+package diff
+
+import "strings"
+
+// change replaces base[start:end] with lines
+type change struct {
+	start, end int
+	lines      []string
+}
+
+// changes lists the regions of base replaced in other
+func changes(base, other []string) []change {
+	var out []change
+	i := 0
+	var cur *change
+	edits, _ := myers(base, other)
+	for _, e := range edits {
+		switch e.Op {
+		case Equal:
+			if cur != nil {
+				out = append(out, *cur)
+				cur = nil
+			}
+			i++
+		case Delete:
+			if cur == nil {
+				cur = &change{start: i, end: i}
+			}
+			cur.end++
+			i++
+		case Insert:
+			if cur == nil {
+				cur = &change{start: i, end: i}
+			}
+			cur.lines = append(cur.lines, e.Text)
+		}
+	}
+	if cur != nil {
+		out = append(out, *cur)
+	}
+	return out
+}
+
+// Merge3 applies the changes from base to theirs onto ours, line by line. It reports false when
+// both sides changed the same or adjacent lines differently.
+func Merge3(base, ours, theirs string) (string, bool) {
+	b := splitLines(base)
+	oursChanges := changes(b, splitLines(ours))
+	theirsChanges := changes(b, splitLines(theirs))
+
+	var sb strings.Builder
+	pos := 0
+	lists := [2]*[]change{&oursChanges, &theirsChanges}
+	for len(oursChanges) > 0 || len(theirsChanges) > 0 {
+		// The earliest change starts a group
+		first := 0
+		if len(oursChanges) == 0 || len(theirsChanges) > 0 && theirsChanges[0].start < oursChanges[0].start {
+			first = 1
+		}
+		var group [2][]change
+		c := (*lists[first])[0]
+		start, end := c.start, c.end
+		group[first] = append(group[first], c)
+		*lists[first] = (*lists[first])[1:]
+
+		// Pull in every change that overlaps or touches the group
+		for grew := true; grew; {
+			grew = false
+			for side, list := range lists {
+				if len(*list) > 0 && (*list)[0].start <= end {
+					group[side] = append(group[side], (*list)[0])
+					end = max(end, (*list)[0].end)
+					*list = (*list)[1:]
+					grew = true
+				}
+			}
+		}
+
+		sb.WriteString(strings.Join(b[pos:start], ""))
+		oursRegion := applyChanges(b, start, end, group[0])
+		theirsRegion := applyChanges(b, start, end, group[1])
+		switch {
+		case len(group[0]) == 0:
+			sb.WriteString(theirsRegion)
+		case len(group[1]) == 0 || oursRegion == theirsRegion:
+			sb.WriteString(oursRegion)
+		default:
+			return "", false
+		}
+		pos = end
+	}
+	sb.WriteString(strings.Join(b[pos:], ""))
+	return sb.String(), true
+}
+
+// applyChanges rebuilds base[start:end] with the given changes of one side
+func applyChanges(base []string, start, end int, list []change) string {
+	var sb strings.Builder
+	pos := start
+	for _, c := range list {
+		sb.WriteString(strings.Join(base[pos:c.start], ""))
+		sb.WriteString(strings.Join(c.lines, ""))
+		pos = c.end
+	}
+	sb.WriteString(strings.Join(base[pos:end], ""))
+	return sb.String()
+}
@@ -0,0 +1,33 @@
+package diff
+
+import "testing"
+
+func TestMerge3(t *testing.T) {
+	base := "a\nb\nc\nd\ne\n"
+	tests := []struct {
+		name         string
+		ours, theirs string
+		want         string
+		wantConflict bool
+	}{
+		{"no changes", base, base, base, false},
+		{"only ours", "a\nB\nc\nd\ne\n", base, "a\nB\nc\nd\ne\n", false},
+		{"only theirs", base, "a\nb\nc\nd\nE\n", "a\nb\nc\nd\nE\n", false},
+		{"separate changes", "A\nb\nc\nd\ne\n", "a\nb\nc\nd\nE\n", "A\nb\nc\nd\nE\n", false},
+		{"same change", "a\nX\nc\nd\ne\n", "a\nX\nc\nd\ne\n", "a\nX\nc\nd\ne\n", false},
+		{"insert and delete", "a\nb\nnew\nc\nd\ne\n", "a\nb\nc\ne\n", "a\nb\nnew\nc\ne\n", false},
+		{"same line changed differently", "a\nX\nc\nd\ne\n", "a\nY\nc\nd\ne\n", "", true},
+		{"adjacent lines changed", "a\nB\nc\nd\ne\n", "a\nb\nC\nd\ne\n", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Merge3(base, tt.ours, tt.theirs)
+			if ok == tt.wantConflict {
+				t.Fatalf("clean = %v, want %v", ok, !tt.wantConflict)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
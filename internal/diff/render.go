@@ -0,0 +1,75 @@
+package diff
+
+import "strings"
+
+const (
+	red   = "\033[31m"
+	green = "\033[32m"
+	reset = "\033[0m"
+)
+
+// RenderWords shows a word diff inline, deletions as [-red-] and insertions as {+green+}
+func RenderWords(edits []Edit, color bool) string {
+	var sb strings.Builder
+	for i, e := range edits {
+		switch e.Op {
+		case Equal:
+			sb.WriteString(e.Text)
+		case Delete:
+			text := e.Text
+			if i+1 < len(edits) && edits[i+1].Op == Insert {
+				// a replacement, the inserted text brings its own whitespace
+				text = strings.TrimRight(text, " \t\r\n")
+			}
+			sb.WriteString(wrap(text, "[-", "-]", red, color))
+		case Insert:
+			sb.WriteString(wrap(e.Text, "{+", "+}", green, color))
+		}
+	}
+	return sb.String()
+}
+
+// RenderLines shows a line diff with -/+ prefixes like diff -u without hunk headers
+func RenderLines(edits []Edit, color bool) string {
+	var sb strings.Builder
+	for _, e := range edits {
+		prefix, code := " ", ""
+		switch e.Op {
+		case Delete:
+			prefix, code = "-", red
+		case Insert:
+			prefix, code = "+", green
+		}
+		for _, line := range splitLines(e.Text) {
+			line = prefix + strings.TrimSuffix(line, "\n")
+			if color && code != "" {
+				line = code + line + reset
+			}
+			sb.WriteString(line + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// Changed reports whether the edits contain any insertion or deletion
+func Changed(edits []Edit) bool {
+	for _, e := range edits {
+		if e.Op != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+// wrap marks a changed run, trailing whitespace stays outside the markers
+func wrap(text, open, close, code string, color bool) string {
+	trimmed := strings.TrimRight(text, " \t\r\n")
+	trail := text[len(trimmed):]
+	if trimmed == "" {
+		return text
+	}
+	if color {
+		return code + open + trimmed + close + reset + trail
+	}
+	return open + trimmed + close + trail
+}
@@ -0,0 +1,114 @@
+package explain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// tools recognizes the program that produced the output, the first match wins
+var tools = []struct {
+	name string
+	re   *regexp.Regexp
+}{
+	{"Go panic", regexp.MustCompile(`(?m)^(panic: |fatal error: |goroutine \d+ \[)`)},
+	{"Go test", regexp.MustCompile(`(?m)^\s*--- FAIL: `)},
+	{"Go compiler", regexp.MustCompile(`(?m)\.go:\d+:\d+: `)},
+	{"Python traceback", regexp.MustCompile(`Traceback \(most recent call last\):`)},
+	{"pytest", regexp.MustCompile(`(?m)^=+ (FAILURES|ERRORS|short test summary info) =+`)},
+	{"Rust", regexp.MustCompile(`(?m)^(error\[E\d+\]|\s+--> .+\.rs:\d+:\d+|thread '.*' panicked at)`)},
+	{"TypeScript compiler", regexp.MustCompile(`error TS\d+:`)},
+	{"Java/JVM stack trace", regexp.MustCompile(`(?m)(^Exception in thread |^\s+at [\w$.<>]+\([\w$]+\.(java|kt|scala):\d+\))`)},
+	{"Node.js stack trace", regexp.MustCompile(`(?m)(^\s+at .+[(/].+\.[cm]?[jt]s:\d+:\d+\)?$|node:internal)`)},
+	{".NET stack trace", regexp.MustCompile(`(?m)^\s+at .+ in .+\.cs:line \d+`)},
+	{"C/C++ compiler", regexp.MustCompile(`\.(c|cc|cpp|cxx|h|hpp):\d+:\d+: (fatal )?(error|warning):`)},
+	{"Ruby", regexp.MustCompile(`\.rb:\d+:in `)},
+	{"PHP", regexp.MustCompile(`PHP (Fatal|Parse|Warning)`)},
+	{"npm", regexp.MustCompile(`(?m)^npm (ERR!|error)`)},
+	{"Docker build", regexp.MustCompile(`(?m)(^Step \d+/\d+ : |^#\d+ \[|failed to solve)`)},
+	{"Kubernetes", regexp.MustCompile(`CrashLoopBackOff|ImagePullBackOff|ErrImagePull|OOMKilled`)},
+	{"application log", regexp.MustCompile(`(?m)\b(ERROR|FATAL|CRITICAL|WARN(ING)?|Exception)\b`)},
+}
+
+// Detect names the language or tool that produced the output, or returns ""
+func Detect(text string) string {
+	for _, t := range tools {
+		if t.re.MatchString(text) {
+			return t.name
+		}
+	}
+	return ""
+}
+
+var (
+	ansiRe   = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+	numberRe = regexp.MustCompile(`0x[0-9a-fA-F]+|\d+`)
+)
+
+// maxPeriod is the longest block of lines (e.g. recursive stack frames) collapsed when repeated
+const maxPeriod = 4
+
+// Clean removes terminal colors and progress bar redraws, trailing spaces and runs of blank lines,
+// and collapses lines or blocks repeated one after the other. Lines differing only in numbers
+// (timestamps, ids, addresses) count as repeated.
+func Clean(text string) string {
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(ansiRe.ReplaceAllString(text, ""), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if i := strings.LastIndex(line, "\r"); i >= 0 {
+			line = line[i+1:] // a redrawn progress line, keep its last state
+		}
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			if blank || len(lines) == 0 {
+				continue
+			}
+			blank = true
+		} else {
+			blank = false
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(collapse(lines), "\n"))
+}
+
+func collapse(lines []string) []string {
+	keys := make([]string, len(lines))
+	for i, line := range lines {
+		keys[i] = numberRe.ReplaceAllString(line, "0")
+	}
+
+	var res []string
+	for i := 0; i < len(lines); {
+		best, bestRepeats := 1, 0
+		for p := 1; p <= maxPeriod && i+2*p <= len(lines); p++ {
+			repeats := 0
+			for j := i + p; j+p <= len(lines) && sameBlock(keys, i, j, p); j += p {
+				repeats++
+			}
+			if repeats > 0 && repeats*p > bestRepeats*best && strings.TrimSpace(lines[i]) != "" {
+				best, bestRepeats = p, repeats
+			}
+		}
+		res = append(res, lines[i:i+best]...)
+		if bestRepeats > 0 {
+			if best == 1 {
+				res = append(res, fmt.Sprintf("[... line repeated %d more time(s)]", bestRepeats))
+			} else {
+				res = append(res, fmt.Sprintf("[... %d lines above repeated %d more time(s)]", best, bestRepeats))
+			}
+		}
+		i += best * (bestRepeats + 1)
+	}
+	return res
+}
+
+func sameBlock(keys []string, a, b, n int) bool {
+	for k := 0; k < n; k++ {
+		if keys[a+k] != keys[b+k] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+package explain
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"colors and trailing spaces", "\x1b[31merror:\x1b[0m failed  \r\n", "error: failed"},
+		{"progress redraws", "Downloading 10%\rDownloading 50%\rDownloading 100%\ndone", "Downloading 100%\ndone"},
+		{"blank lines", "\n\na\n\n\n\nb\n\n", "a\n\nb"},
+		{"repeated line", "start\nretrying\nretrying\nretrying\nend", "start\nretrying\n[... line repeated 2 more time(s)]\nend"},
+		{"lines differing in numbers", "12:00:01 timeout id=7\n12:00:02 timeout id=8\n12:00:03 timeout id=9", "12:00:01 timeout id=7\n[... line repeated 2 more time(s)]"},
+		{"repeated block", "f()\n  at a.go:1\n  at b.go:2\n  at a.go:1\n  at b.go:2\n  at a.go:1\n  at b.go:2\nend", "f()\n  at a.go:1\n  at b.go:2\n[... 2 lines above repeated 2 more time(s)]\nend"},
+		{"no repeats", "a\nb\nc", "a\nb\nc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Clean(tt.text); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// A deep recursion collapses to one block
+	var trace strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&trace, "main.recurse(0x%x)\n\t/src/main.go:12 +0x%x\n", i, i)
+	}
+	if got := strings.Count(Clean(trace.String()), "\n"); got != 2 {
+		t.Errorf("recursion collapsed to %d lines", got+1)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"panic: runtime error: index out of range\n\ngoroutine 1 [running]:", "Go panic"},
+		{"--- FAIL: TestX (0.00s)\n    x_test.go:10: bad", "Go test"},
+		{"./main.go:5:2: undefined: foo", "Go compiler"},
+		{"Traceback (most recent call last):\n  File \"app.py\", line 3, in <module>", "Python traceback"},
+		{"error[E0382]: borrow of moved value", "Rust"},
+		{"src/app.ts(4,7): error TS2322: Type 'string' is not assignable", "TypeScript compiler"},
+		{"Exception in thread \"main\" java.lang.NullPointerException\n\tat App.main(App.java:5)", "Java/JVM stack trace"},
+		{"main.c:3:5: error: expected ';'", "C/C++ compiler"},
+		{"2024-01-01 ERROR connection refused", "application log"},
+		{"everything is fine", ""},
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.text); got != tt.want {
+			t.Errorf("Detect(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestReferences(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Traceback:\n  File \"app/main.py\", line 12, in run\n  File \"app/main.py\", line 12, in run", "app/main.py:12"},
+		{"./cmd/main.go:5:2: undefined\n/src/util.go:40 +0x1d", "./cmd/main.go:5 /src/util.go:40"},
+		{"   at App.Run() in C:\\src\\App.cs:line 7", "C:\\src\\App.cs:7"},
+		{"src/app.ts(4,7): error TS2322", "src/app.ts:4"},
+		{"no locations", ""},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, r := range References(tt.text) {
+			got = append(got, fmt.Sprintf("%s:%d", r.Path, r.Line))
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("References(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	content := "l1\nl2\nl3\nl4\nl5\nl6\nl7\nl8\nl9\nl10\n"
+	tests := []struct {
+		lines []int
+		want  string
+	}{
+		{[]int{2}, "     1 | l1\n>    2 | l2\n     3 | l3\n"},
+		{[]int{1, 9}, ">    1 | l1\n     2 | l2\n     ...\n     8 | l8\n>    9 | l9\n    10 | l10\n"},
+		{[]int{5, 4}, "     3 | l3\n>    4 | l4\n>    5 | l5\n     6 | l6\n"},
+		{[]int{0, 42}, ""},
+	}
+	for _, tt := range tests {
+		if got := Snippet(content, tt.lines, 1); got != tt.want {
+			t.Errorf("Snippet(%v) =\n%s\nwant\n%s", tt.lines, got, tt.want)
+		}
+	}
+}
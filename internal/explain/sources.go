@@ -0,0 +1,90 @@
+package explain
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Ref is a source location mentioned in the output
+type Ref struct {
+	Path string
+	Line int
+}
+
+// refRes find path and line pairs: Python frames, .NET frames, tsc positions, then file:line
+var refRes = []*regexp.Regexp{
+	regexp.MustCompile(`File "([^"]+)", line (\d+)`),
+	regexp.MustCompile(` in (\S+\.cs):line (\d+)`),
+	regexp.MustCompile(`([\w./\\@~+-]+\.tsx?)\((\d+),\d+\)`),
+	regexp.MustCompile(`([\w./\\@~+-]*[\w-]\.[A-Za-z]{1,5}):(\d+)`),
+}
+
+// References returns the source locations of the output in order of appearance, without
+// duplicates. The paths are not checked, they may point outside the project or be URLs.
+func References(text string) []Ref {
+	type found struct {
+		pos int
+		ref Ref
+	}
+	var all []found
+	taken := map[int]bool{} // start offsets already matched by a more specific pattern
+	for _, re := range refRes {
+		for _, m := range re.FindAllStringSubmatchIndex(text, -1) {
+			if taken[m[2]] {
+				continue
+			}
+			taken[m[2]] = true
+			line, _ := strconv.Atoi(text[m[4]:m[5]])
+			all = append(all, found{m[2], Ref{Path: text[m[2]:m[3]], Line: line}})
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].pos < all[j].pos })
+
+	var refs []Ref
+	seen := map[Ref]bool{}
+	for _, f := range all {
+		if f.ref.Line > 0 && !seen[f.ref] {
+			seen[f.ref] = true
+			refs = append(refs, f.ref)
+		}
+	}
+	return refs
+}
+
+// Snippet returns the lines of content around the referenced lines, numbered and with the
+// referenced lines marked by ">". Windows that overlap are merged.
+func Snippet(content string, lines []int, context int) string {
+	src := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	marked := map[int]bool{}
+	for _, l := range lines {
+		marked[l] = true
+	}
+	sort.Ints(lines)
+
+	var b strings.Builder
+	last := 0 // last line written
+	for _, l := range lines {
+		if l < 1 || l > len(src) {
+			continue
+		}
+		from, to := max(l-context, last+1, 1), min(l+context, len(src))
+		if from > to {
+			continue
+		}
+		if last > 0 && from > last+1 {
+			b.WriteString("     ...\n")
+		}
+		for n := from; n <= to; n++ {
+			mark := " "
+			if marked[n] {
+				mark = ">"
+			}
+			fmt.Fprintf(&b, "%s%5d | %s\n", mark, n, src[n-1])
+		}
+		last = to
+	}
+	return b.String()
+}
@@ -0,0 +1,120 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// Kind is the detected document type of an input file
+type Kind string
+
+const (
+	Plain Kind = "text"
+	PDF   Kind = "pdf"
+	DOCX  Kind = "docx"
+	ODT   Kind = "odt"
+	XLSX  Kind = "xlsx"
+	HTML  Kind = "html"
+)
+
+// Detect guesses the document type from the file content, using the extension as a hint
+func Detect(path string, data []byte) Kind {
+	ext := strings.ToLower(filepath.Ext(path))
+	contentType := http.DetectContentType(data)
+
+	switch {
+	case strings.HasPrefix(contentType, "application/pdf"):
+		return PDF
+	case strings.HasPrefix(contentType, "application/zip"):
+		return detectZip(data, ext)
+	case strings.HasPrefix(contentType, "text/html"), ext == ".html", ext == ".htm", ext == ".xhtml":
+		return HTML
+	}
+	return Plain
+}
+
+// Binary reports whether the start of a file holds NUL bytes and is no PDF or zip archive, so
+// the file is neither text nor a document the package reads
+func Binary(head []byte) bool {
+	contentType := http.DetectContentType(head)
+	return bytes.IndexByte(head, 0) >= 0 && !strings.HasPrefix(contentType, "application/pdf") &&
+		!strings.HasPrefix(contentType, "application/zip")
+}
+
+// Office documents are zip archives, tell them apart by their entries
+func detectZip(data []byte, ext string) Kind {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return Plain
+	}
+	for _, f := range r.File {
+		switch f.Name {
+		case "word/document.xml":
+			return DOCX
+		case "xl/workbook.xml":
+			return XLSX
+		case "content.xml":
+			if ext == ".odt" || ext == "" {
+				return ODT
+			}
+		}
+	}
+	return Plain
+}
+
+// errExpanded is returned when the compressed parts of a document inflate past the limit
+var errExpanded = errors.New("document expands beyond the size limit")
+
+// readLimited reads r whole, counting the bytes against the budget shared by all the parts
+// of a document, so a zip or deflate bomb stops at the limit instead of filling the memory
+func readLimited(r io.Reader, budget *int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, *budget+1))
+	if int64(len(data)) > *budget {
+		return nil, errExpanded
+	}
+	*budget -= int64(len(data))
+	return data, err
+}
+
+// Text extracts readable text from a document, plain text is returned unchanged. HTML is only
+// reduced to its text with extractHTML, otherwise the markup is plain text.
+// maxExpanded caps the bytes inflated from the compressed parts of the document.
+func Text(path string, data []byte, maxExpanded int64, extractHTML bool) (string, Kind, error) {
+	kind := Detect(path, data)
+	budget := maxExpanded
+
+	var text string
+	var err error
+	switch kind {
+	case PDF:
+		text, err = pdfText(data, &budget)
+	case DOCX:
+		text, err = docxText(data, &budget)
+	case ODT:
+		text, err = odtText(data, &budget)
+	case XLSX:
+		text, err = xlsxText(data, &budget)
+	case HTML:
+		if !extractHTML {
+			return string(data), Plain, nil
+		}
+		text = htmlText(string(data))
+	default:
+		return string(data), kind, nil
+	}
+	if err != nil {
+		return "", kind, fmt.Errorf("failed to extract %s text: %w", kind, err)
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", kind, fmt.Errorf("no extractable text in %s file", kind)
+	}
+	return text, kind, nil
+}
@@ -0,0 +1,192 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const testLimit = 1 << 20
+
+func zipFile(t *testing.T, entries map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range entries {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func pdfFile(content string, deflate bool) []byte {
+	stream, dict := []byte(content), ""
+	if deflate {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		zw.Write(stream)
+		zw.Close()
+		stream, dict = buf.Bytes(), " /Filter /FlateDecode"
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%%PDF-1.4\n1 0 obj\n<< /Length %d%s >>\nstream\n", len(stream), dict)
+	b.Write(stream)
+	b.WriteString("\nendstream\nendobj\n%%EOF\n")
+	return b.Bytes()
+}
+
+func TestPDFText(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		deflate bool
+		want    string
+	}{
+		{"literal", "BT /F1 12 Tf (Hello World) Tj ET", false, "Hello World"},
+		{"deflate", "BT (Compressed) Tj ET", true, "Compressed"},
+		{"two-byte codes", `BT (\000H\000i) Tj ET`, false, "Hi"},
+		{"escapes", `BT (a\(b\) \101) Tj ET`, false, "a(b) A"},
+		{"hex", "BT <48656C6C6F> Tj ET", false, "Hello"},
+		{"hex two-byte codes", "BT <00480069> Tj ET", false, "Hi"},
+		{"array", "BT [(Hel) -20 (lo)] TJ ET", false, "Hello"},
+		{"lines", "BT (one) Tj T* (two) Tj ET", false, "one\ntwo"},
+		{"outside text object", "(ignored) Tj", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget := int64(testLimit)
+			got, err := pdfText(pdfFile(tt.content, tt.deflate), &budget)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got = strings.TrimSpace(got); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOfficeText(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		entries map[string]string
+		kind    Kind
+		want    string
+	}{
+		{
+			name: "docx",
+			path: "a.docx",
+			entries: map[string]string{"word/document.xml": `<w:document xmlns:w="w"><w:body>` +
+				`<w:p><w:r><w:t>First</w:t><w:tab/><w:t>line</w:t></w:r></w:p>` +
+				`<w:p><w:r><w:t>Second</w:t></w:r></w:p></w:body></w:document>`},
+			kind: DOCX,
+			want: "First\tline\nSecond",
+		},
+		{
+			name: "odt",
+			path: "a.odt",
+			entries: map[string]string{"content.xml": `<office:document-content xmlns:office="o" xmlns:text="t">` +
+				`<office:body><office:text><text:h>Title</text:h><text:p>A<text:s/>B</text:p></office:text></office:body>` +
+				`</office:document-content>`},
+			kind: ODT,
+			want: "Title\nA B",
+		},
+		{
+			name: "xlsx",
+			path: "a.xlsx",
+			entries: map[string]string{
+				"xl/workbook.xml":          `<workbook/>`,
+				"xl/sharedStrings.xml":     `<sst><si><t>name</t></si><si><r><t>ri</t></r><r><t>ch</t></r></si></sst>`,
+				"xl/worksheets/sheet1.xml": `<worksheet><sheetData><row><c t="s"><v>0</v></c><c><v>42</v></c></row><row><c t="s"><v>1</v></c><c t="inlineStr"><is><t>inline</t></is></c></row></sheetData></worksheet>`,
+			},
+			kind: XLSX,
+			want: "# Sheet 1\nname\t42\nrich\tinline",
+		},
+		{
+			name: "xlsx shared string index out of range",
+			path: "a.xlsx",
+			entries: map[string]string{
+				"xl/workbook.xml":          `<workbook/>`,
+				"xl/sharedStrings.xml":     `<sst><si><t>only</t></si></sst>`,
+				"xl/worksheets/sheet1.xml": `<worksheet><sheetData><row><c t="s"><v>-1</v></c><c t="s"><v>5</v></c><c t="s"><v>0</v></c></row></sheetData></worksheet>`,
+			},
+			kind: XLSX,
+			want: "# Sheet 1\n\t\tonly",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, kind, err := Text(tt.path, zipFile(t, tt.entries), testLimit, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kind != tt.kind {
+				t.Errorf("kind %s, want %s", kind, tt.kind)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandedLimit(t *testing.T) {
+	big := strings.Repeat("a", 2*testLimit)
+	tests := []struct {
+		name string
+		path string
+		data []byte
+	}{
+		{"docx", "bomb.docx", zipFile(t, map[string]string{"word/document.xml": "<w:t>" + big + "</w:t>"})},
+		{"pdf", "bomb.pdf", pdfFile("BT ("+big+") Tj ET", true)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Text(tt.path, tt.data, testLimit, false)
+			if !errors.Is(err, errExpanded) {
+				t.Errorf("got %v, want %v", err, errExpanded)
+			}
+		})
+	}
+}
+
+func TestHTMLText(t *testing.T) {
+	doc := `<html><head><title>T</title><style>p{}</style></head><body>
+<!-- comment --><h1>Title</h1><p>One &amp; <b>two</b></p><script>x()</script><ul><li>item</li></ul></body></html>`
+	got, kind, err := Text("page.html", []byte(doc), testLimit, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kind != HTML {
+		t.Errorf("kind %s, want %s", kind, HTML)
+	}
+	if want := "Title\n\nOne & two\n\nitem"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Without extraction the markup is sent as is, e.g. to rewrite or review it
+	got, kind, err = Text("page.html", []byte(doc), testLimit, false)
+	if err != nil || kind != Plain || got != doc {
+		t.Errorf("got %q, %s, %v, want the markup", got, kind, err)
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	got, kind, err := Text("notes.txt", []byte("just text\n"), testLimit, false)
+	if err != nil || kind != Plain || got != "just text\n" {
+		t.Errorf("got %q, %s, %v", got, kind, err)
+	}
+}
@@ -0,0 +1,27 @@
+package extract
+
+import (
+	"html"
+	"regexp"
+)
+
+var (
+	htmlSkipRe    = regexp.MustCompile(`(?is)<(script|style|noscript|head)\b.*?</(script|style|noscript|head)>`)
+	htmlCommentRe = regexp.MustCompile(`(?s)<!--.*?-->`)
+	htmlBlockRe   = regexp.MustCompile(`(?i)</?(p|div|br|li|tr|h[1-6]|table|ul|ol|section|article|header|footer|pre|blockquote)\b[^>]*>`)
+	htmlTagRe     = regexp.MustCompile(`(?s)<[^>]*>`)
+	blankLinesRe  = regexp.MustCompile(`\n[ \t]*(\n[ \t]*)+`)
+	spacesRe      = regexp.MustCompile(`[ \t]+`)
+)
+
+// htmlText strips markup, keeping block elements on separate lines
+func htmlText(doc string) string {
+	doc = htmlCommentRe.ReplaceAllString(doc, "")
+	doc = htmlSkipRe.ReplaceAllString(doc, "")
+	doc = htmlBlockRe.ReplaceAllString(doc, "\n")
+	doc = htmlTagRe.ReplaceAllString(doc, "")
+	doc = html.UnescapeString(doc)
+	doc = spacesRe.ReplaceAllString(doc, " ")
+	doc = blankLinesRe.ReplaceAllString(doc, "\n\n")
+	return doc
+}
@@ -0,0 +1,221 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func readZipEntry(r *zip.Reader, name string, budget *int64) ([]byte, error) {
+	f, err := r.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readLimited(f, budget)
+}
+
+// docxText walks word/document.xml, paragraphs become lines
+func docxText(data []byte, budget *int64) (string, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	doc, err := readZipEntry(r, "word/document.xml", budget)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	dec := xml.NewDecoder(bytes.NewReader(doc))
+	inText := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				sb.WriteString("\t")
+			case "br", "cr":
+				sb.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				sb.WriteString("\n")
+			}
+		case xml.CharData:
+			if inText {
+				sb.Write(t)
+			}
+		}
+	}
+	return sb.String(), nil
+}
+
+// odtText walks content.xml of an OpenDocument text file
+func odtText(data []byte, budget *int64) (string, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	doc, err := readZipEntry(r, "content.xml", budget)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	dec := xml.NewDecoder(bytes.NewReader(doc))
+	depth := 0 // depth inside office:text
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "text" && depth == 0 {
+				depth = 1
+				continue
+			}
+			if depth > 0 {
+				depth++
+				switch t.Name.Local {
+				case "s":
+					sb.WriteString(" ")
+				case "tab":
+					sb.WriteString("\t")
+				case "line-break":
+					sb.WriteString("\n")
+				}
+			}
+		case xml.EndElement:
+			if depth > 0 {
+				depth--
+				if t.Name.Local == "p" || t.Name.Local == "h" {
+					sb.WriteString("\n")
+				}
+			}
+		case xml.CharData:
+			if depth > 0 {
+				sb.Write(t)
+			}
+		}
+	}
+	return sb.String(), nil
+}
+
+type xlsxSharedStrings struct {
+	Items []struct {
+		Text string `xml:"t"`
+		Runs []struct {
+			Text string `xml:"t"`
+		} `xml:"r"`
+	} `xml:"si"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		Cells []struct {
+			Type   string `xml:"t,attr"`
+			Value  string `xml:"v"`
+			Inline struct {
+				Text string `xml:"t"`
+			} `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// xlsxText renders every worksheet as tab separated rows
+func xlsxText(data []byte, budget *int64) (string, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+
+	var shared []string
+	raw, err := readZipEntry(r, "xl/sharedStrings.xml", budget)
+	if errors.Is(err, errExpanded) {
+		return "", err
+	}
+	if err == nil {
+		var ss xlsxSharedStrings
+		if err := xml.Unmarshal(raw, &ss); err != nil {
+			return "", err
+		}
+		for _, item := range ss.Items {
+			text := item.Text
+			for _, run := range item.Runs {
+				text += run.Text
+			}
+			shared = append(shared, text)
+		}
+	}
+
+	// Sheets are stored as xl/worksheets/sheet1.xml, sheet2.xml, ...
+	var sheets []int
+	for _, f := range r.File {
+		name := strings.TrimPrefix(f.Name, "xl/worksheets/sheet")
+		if name == f.Name || !strings.HasSuffix(name, ".xml") {
+			continue
+		}
+		if n, err := strconv.Atoi(strings.TrimSuffix(name, ".xml")); err == nil {
+			sheets = append(sheets, n)
+		}
+	}
+	sort.Ints(sheets)
+
+	var sb strings.Builder
+	for _, n := range sheets {
+		raw, err := readZipEntry(r, fmt.Sprintf("xl/worksheets/sheet%d.xml", n), budget)
+		if err != nil {
+			return "", err
+		}
+		var sheet xlsxSheet
+		if err := xml.Unmarshal(raw, &sheet); err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(&sb, "# Sheet %d\n", n)
+		for _, row := range sheet.Rows {
+			var cells []string
+			for _, c := range row.Cells {
+				switch c.Type {
+				case "s":
+					idx, err := strconv.Atoi(c.Value)
+					if err == nil && idx >= 0 && idx < len(shared) {
+						cells = append(cells, shared[idx])
+					} else {
+						cells = append(cells, "")
+					}
+				case "inlineStr":
+					cells = append(cells, c.Inline.Text)
+				default:
+					cells = append(cells, c.Value)
+				}
+			}
+			sb.WriteString(strings.Join(cells, "\t") + "\n")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,234 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var pdfStreamRe = regexp.MustCompile(`(?s)<<(.*?)>>\s*stream\r?\n`)
+
+// pdfText is a best-effort extractor for text-based PDFs. It decodes content
+// streams and collects the strings shown by the text operators (Tj, TJ, ', ").
+// Scanned PDFs and fonts with custom encodings will yield little or no text.
+func pdfText(data []byte, budget *int64) (string, error) {
+	if !bytes.HasPrefix(data, []byte("%PDF")) {
+		return "", errors.New("not a PDF file")
+	}
+
+	var sb strings.Builder
+	for _, loc := range pdfStreamRe.FindAllSubmatchIndex(data, -1) {
+		dict := data[loc[2]:loc[3]]
+		start := loc[1]
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		stream := data[start : start+end]
+
+		// Skip images, fonts and other binary payloads
+		if bytes.Contains(dict, []byte("/Subtype")) || bytes.Contains(dict, []byte("/Length1")) {
+			continue
+		}
+		if bytes.Contains(dict, []byte("/FlateDecode")) {
+			zr, err := zlib.NewReader(bytes.NewReader(stream))
+			if err != nil {
+				continue
+			}
+			decoded, err := readLimited(zr, budget)
+			zr.Close()
+			if errors.Is(err, errExpanded) {
+				return "", err
+			}
+			if err != nil && len(decoded) == 0 {
+				continue
+			}
+			stream = decoded
+		} else if bytes.Contains(dict, []byte("/Filter")) {
+			continue // unsupported filter
+		}
+
+		sb.WriteString(pdfContentText(stream))
+	}
+	return sb.String(), nil
+}
+
+// pdfContentText interprets the text operators of a single content stream
+func pdfContentText(stream []byte) string {
+	var sb strings.Builder
+	var operands []string
+	inText := false
+
+	for i := 0; i < len(stream); {
+		c := stream[i]
+		switch {
+		case c == '(':
+			s, n := pdfLiteral(stream[i:])
+			operands = append(operands, s)
+			i += n
+		case c == '<' && i+1 < len(stream) && stream[i+1] != '<':
+			s, n := pdfHex(stream[i:])
+			operands = append(operands, s)
+			i += n
+		case c == '[' || c == ']':
+			i++
+		case c == '%':
+			for i < len(stream) && stream[i] != '\n' && stream[i] != '\r' {
+				i++
+			}
+		case isPDFSpace(c):
+			i++
+		default:
+			start := i
+			for i < len(stream) && !isPDFSpace(stream[i]) && !strings.ContainsRune("()<>[]/%", rune(stream[i])) {
+				i++
+			}
+			if i == start {
+				i++ // lone delimiter such as a name slash
+				continue
+			}
+			op := string(stream[start:i])
+			switch op {
+			case "BT":
+				inText = true
+			case "ET":
+				inText = false
+				sb.WriteString("\n")
+			case "Tj", "TJ":
+				if inText {
+					sb.WriteString(latin1(strings.Join(operands, "")))
+				}
+			case "'", "\"":
+				if inText {
+					sb.WriteString("\n" + latin1(strings.Join(operands, "")))
+				}
+			case "Td", "TD", "T*":
+				if inText {
+					sb.WriteString("\n")
+				}
+			}
+			if !isPDFNumber(op) {
+				operands = operands[:0]
+			}
+		}
+	}
+	return sb.String()
+}
+
+// pdfLiteral decodes a (...) string, returning it and the bytes consumed
+func pdfLiteral(b []byte) (string, int) {
+	var sb strings.Builder
+	depth := 0
+	i := 0
+	for ; i < len(b); i++ {
+		c := b[i]
+		switch c {
+		case '(':
+			depth++
+			if depth == 1 {
+				continue
+			}
+		case ')':
+			depth--
+			if depth == 0 {
+				return sb.String(), i + 1
+			}
+		case '\\':
+			i++
+			if i >= len(b) {
+				return sb.String(), i
+			}
+			switch e := b[i]; e {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					v := 0
+					j := 0
+					for ; j < 3 && i+j < len(b) && b[i+j] >= '0' && b[i+j] <= '7'; j++ {
+						v = v*8 + int(b[i+j]-'0')
+					}
+					i += j - 1
+					if v != 0 { // high byte of a two-byte code
+						sb.WriteByte(byte(v))
+					}
+				} else {
+					sb.WriteByte(e)
+				}
+			}
+			continue
+		}
+		if c != 0 {
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String(), i
+}
+
+// pdfHex decodes a <...> string, returning it and the bytes consumed
+func pdfHex(b []byte) (string, int) {
+	end := bytes.IndexByte(b, '>')
+	if end < 0 {
+		return "", len(b)
+	}
+	var digits []byte
+	for _, c := range b[1:end] {
+		if !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	var sb strings.Builder
+	for i := 0; i < len(digits); i += 2 {
+		v := hexVal(digits[i])<<4 | hexVal(digits[i+1])
+		if v != 0 {
+			sb.WriteByte(v)
+		}
+	}
+	return sb.String(), end + 1
+}
+
+// latin1 maps raw string bytes to runes, PDF standard encodings are close to Latin-1
+func latin1(s string) string {
+	runes := make([]rune, len(s))
+	for i := 0; i < len(s); i++ {
+		runes[i] = rune(s[i])
+	}
+	return string(runes)
+}
+
+func hexVal(c byte) byte {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0'
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10
+	}
+	return 0
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFNumber(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && c != '.' && c != '-' && c != '+' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,109 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// Run runs git in the current directory and returns its output
+func Run(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
+
+// Diff returns the diff of the staged changes, or of a revision range such as main..HEAD,
+// limited to the pathspecs and leaving out the excluded patterns (e.g. go.sum, *.lock)
+func Diff(rangeSpec string, staged bool, pathspecs, exclude []string) (string, error) {
+	args := []string{"diff", "--no-color", "--no-ext-diff", "--find-renames"}
+	if staged {
+		args = append(args, "--staged")
+	}
+	if rangeSpec != "" {
+		args = append(args, rangeSpec)
+	}
+	args = append(args, "--")
+	args = append(args, pathspecs...)
+	// Without pathspecs the diff covers the whole repository, also when run from a subdirectory
+	if len(pathspecs) == 0 && len(exclude) > 0 {
+		args = append(args, ":/")
+	}
+	for _, pattern := range exclude {
+		args = append(args, ":(top,exclude,glob)**/"+pattern)
+	}
+	return Run(args...)
+}
+
+// FileDiff is the part of a diff about one file
+type FileDiff struct {
+	Path string
+	Diff string
+}
+
+var diffHeaderRe = regexp.MustCompile(`(?m)^diff --git a/(.*) b/(.*)$`)
+
+// Split cuts a diff into one section per file
+func Split(diff string) []FileDiff {
+	locs := diffHeaderRe.FindAllStringSubmatchIndex(diff, -1)
+	files := make([]FileDiff, len(locs))
+	for i, loc := range locs {
+		end := len(diff)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		files[i] = FileDiff{Path: diff[loc[4]:loc[5]], Diff: diff[loc[0]:end]}
+	}
+	return files
+}
+
+// Commit runs git commit with the message. With edit, git opens the message in its editor first
+// and aborts the commit when it is emptied.
+func Commit(message string, edit bool) error {
+	cmd := exec.Command("git", "commit", "-F", "-")
+	cmd.Stdin = strings.NewReader(message)
+	if edit {
+		f, err := os.CreateTemp("", "COMMIT_EDITMSG-*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(f.Name())
+		if _, err := f.WriteString(message); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		// The editor needs the terminal
+		cmd = exec.Command("git", "commit", "-e", "-F", f.Name())
+		cmd.Stdin = os.Stdin
+	}
+	// Keep stdout for the message
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git commit: %w", err)
+	}
+	return nil
+}
+
+// IsRevision reports whether s is a revision range (base..head, base...head) or names a commit
+func IsRevision(s string) bool {
+	if strings.Contains(s, "..") {
+		return true
+	}
+	_, err := Run("rev-parse", "--verify", "--quiet", s+"^{commit}")
+	return err == nil
+}
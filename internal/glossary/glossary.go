@@ -0,0 +1,236 @@
+package glossary
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Glossary holds the required translation of terms per language and the terms that must stay as-is
+type Glossary struct {
+	Terms          map[string]map[string]string `yaml:"terms"` // term -> language -> translation
+	DoNotTranslate []string                     `yaml:"doNotTranslate"`
+}
+
+// Violation is a glossary rule the translation did not follow
+type Violation struct {
+	Term     string
+	Expected string
+}
+
+func (v Violation) String() string {
+	if v.Term == v.Expected {
+		return fmt.Sprintf("%q must not be translated", v.Term)
+	}
+	return fmt.Sprintf("%q should be translated as %q", v.Term, v.Expected)
+}
+
+// Load reads a YAML glossary, or a CSV with a "term,<language>,..." header where
+// terms without any translation are not to be translated
+func Load(path string) (*Glossary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read glossary: %w", err)
+	}
+
+	g := &Glossary{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, g); err != nil {
+			return nil, fmt.Errorf("invalid glossary: %w", err)
+		}
+	case ".csv":
+		if err := g.loadCSV(string(data)); err != nil {
+			return nil, fmt.Errorf("invalid glossary: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported glossary format %q (yaml, csv)", filepath.Ext(path))
+	}
+	return g, nil
+}
+
+func (g *Glossary) loadCSV(data string) error {
+	records, err := csv.NewReader(strings.NewReader(data)).ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 || len(records[0]) == 0 {
+		return fmt.Errorf("missing header row")
+	}
+
+	header := records[0]
+	g.Terms = map[string]map[string]string{}
+	for _, row := range records[1:] {
+		term := strings.TrimSpace(row[0])
+		if term == "" {
+			continue
+		}
+		translations := map[string]string{}
+		for i := 1; i < len(row) && i < len(header); i++ {
+			if value := strings.TrimSpace(row[i]); value != "" {
+				translations[strings.TrimSpace(header[i])] = value
+			}
+		}
+		if len(translations) == 0 {
+			g.DoNotTranslate = append(g.DoNotTranslate, term)
+		} else {
+			g.Terms[term] = translations
+		}
+	}
+	return nil
+}
+
+// languageNames maps ISO 639-1 codes to the English language names used with -l
+var languageNames = map[string]string{
+	"ar": "arabic", "bg": "bulgarian", "ca": "catalan", "cs": "czech", "da": "danish", "de": "german",
+	"el": "greek", "en": "english", "es": "spanish", "et": "estonian", "fa": "persian", "fi": "finnish",
+	"fr": "french", "he": "hebrew", "hi": "hindi", "hr": "croatian", "hu": "hungarian", "id": "indonesian",
+	"it": "italian", "ja": "japanese", "ko": "korean", "lt": "lithuanian", "lv": "latvian", "ms": "malay",
+	"nb": "norwegian", "nl": "dutch", "no": "norwegian", "pl": "polish", "pt": "portuguese", "ro": "romanian",
+	"ru": "russian", "sk": "slovak", "sl": "slovenian", "sr": "serbian", "sv": "swedish", "th": "thai",
+	"tr": "turkish", "uk": "ukrainian", "vi": "vietnamese", "zh": "chinese",
+}
+
+// sameLanguage compares languages case-insensitively, as codes or names (fr, fr-CA, French)
+func sameLanguage(a, b string) bool {
+	return languageName(a) == languageName(b)
+}
+
+func languageName(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	code, _, _ := strings.Cut(strings.ReplaceAll(lang, "_", "-"), "-")
+	if name, ok := languageNames[code]; ok {
+		return name
+	}
+	return lang
+}
+
+// translation returns the required translation of term for a language
+func (g *Glossary) translation(term, language string) string {
+	for lang, value := range g.Terms[term] {
+		if sameLanguage(lang, language) {
+			return value
+		}
+	}
+	return ""
+}
+
+// HasLanguage reports whether the glossary has translations for the language. A glossary
+// without any, only listing terms not to translate, applies to every language.
+func (g *Glossary) HasLanguage(language string) bool {
+	if g == nil || len(g.Terms) == 0 {
+		return true
+	}
+	for _, translations := range g.Terms {
+		for lang := range translations {
+			if sameLanguage(lang, language) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// containsWord reports whether term occurs in text as a whole word: "Go" is not found in
+// "good". Terms starting or ending with a symbol (C++, .NET) only match as written there.
+func containsWord(text, term string, fold bool) bool {
+	if fold {
+		text, term = strings.ToLower(text), strings.ToLower(term)
+	}
+	if term == "" {
+		return false
+	}
+	first, _ := utf8.DecodeRuneInString(term)
+	last, _ := utf8.DecodeLastRuneInString(term)
+	for from := 0; ; {
+		i := strings.Index(text[from:], term)
+		if i < 0 {
+			return false
+		}
+		start, end := from+i, from+i+len(term)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if (start == 0 || !isWordRune(first) || !isWordRune(before)) && (end == len(text) || !isWordRune(last) || !isWordRune(after)) {
+			return true
+		}
+		from = start + utf8.RuneLen(first)
+	}
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// rules lists the glossary entries that occur in the source text, sorted for a stable prompt
+func (g *Glossary) rules(source, language string) (terms map[string]string, keep []string) {
+	terms = map[string]string{}
+	for term := range g.Terms {
+		if value := g.translation(term, language); value != "" && containsWord(source, term, true) {
+			terms[term] = value
+		}
+	}
+	for _, term := range g.DoNotTranslate {
+		if containsWord(source, term, false) {
+			keep = append(keep, term)
+		}
+	}
+	sort.Strings(keep)
+	return terms, keep
+}
+
+// Instructions are added to the translate prompt, only for terms found in the source
+func (g *Glossary) Instructions(source, language string) string {
+	if g == nil {
+		return ""
+	}
+	terms, keep := g.rules(source, language)
+
+	var sb strings.Builder
+	if len(terms) > 0 {
+		sb.WriteString("Use this glossary:\n")
+		var names []string
+		for term := range terms {
+			names = append(names, term)
+		}
+		sort.Strings(names)
+		for _, term := range names {
+			fmt.Fprintf(&sb, "- %q -> %q\n", term, terms[term])
+		}
+	}
+	if len(keep) > 0 {
+		sb.WriteString("Keep these terms exactly as written, do not translate them:\n")
+		for _, term := range keep {
+			fmt.Fprintf(&sb, "- %q\n", term)
+		}
+	}
+	return sb.String()
+}
+
+// Verify checks that the translation follows the glossary rules for the terms found in the source
+func (g *Glossary) Verify(source, translated, language string) []Violation {
+	if g == nil {
+		return nil
+	}
+	terms, keep := g.rules(source, language)
+
+	var violations []Violation
+	for term, value := range terms {
+		if !containsWord(translated, value, true) {
+			violations = append(violations, Violation{Term: term, Expected: value})
+		}
+	}
+	for _, term := range keep {
+		if !containsWord(translated, term, false) {
+			violations = append(violations, Violation{Term: term, Expected: term})
+		}
+	}
+	sort.Slice(violations, func(i, j int) bool { return violations[i].Term < violations[j].Term })
+	return violations
+}
@@ -0,0 +1,123 @@
+package glossary
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func load(t *testing.T, name, content string) *Glossary {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	g, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestLoad(t *testing.T) {
+	csv := load(t, "g.csv", "term,fr,de\ninvoice,facture,Rechnung\nGo,,\n")
+	yml := load(t, "g.yaml", "doNotTranslate: [Go]\nterms:\n  invoice:\n    fr: facture\n    de: Rechnung\n")
+	for name, g := range map[string]*Glossary{"csv": csv, "yaml": yml} {
+		if got := g.translation("invoice", "de"); got != "Rechnung" {
+			t.Errorf("%s: invoice in de = %q", name, got)
+		}
+		if len(g.DoNotTranslate) != 1 || g.DoNotTranslate[0] != "Go" {
+			t.Errorf("%s: doNotTranslate = %q", name, g.DoNotTranslate)
+		}
+	}
+}
+
+func TestLanguages(t *testing.T) {
+	g := &Glossary{Terms: map[string]map[string]string{"invoice": {"fr": "facture", "pt-BR": "fatura"}}}
+	tests := []struct {
+		language string
+		want     string
+	}{
+		{"fr", "facture"},
+		{"FR", "facture"},
+		{"French", "facture"},
+		{"fr-CA", "facture"},
+		{"Portuguese", "fatura"},
+		{"pt_BR", "fatura"},
+		{"German", ""},
+	}
+	for _, tt := range tests {
+		if got := g.translation("invoice", tt.language); got != tt.want {
+			t.Errorf("translation for %s = %q, want %q", tt.language, got, tt.want)
+		}
+		if got := g.HasLanguage(tt.language); got != (tt.want != "") {
+			t.Errorf("HasLanguage(%s) = %v", tt.language, got)
+		}
+	}
+	if keepOnly := (&Glossary{DoNotTranslate: []string{"Go"}}); !keepOnly.HasLanguage("German") {
+		t.Error("a glossary without translations applies to every language")
+	}
+}
+
+func TestContainsWord(t *testing.T) {
+	tests := []struct {
+		text, term string
+		fold, want bool
+	}{
+		{"We use Go here", "Go", false, true},
+		{"This is good", "Go", false, false},
+		{"long ago", "Go", true, false},
+		{"Go, then stop", "Go", false, true},
+		{"golang and go", "Go", true, true},
+		{"Acme Cloud rocks", "Acme Cloud", false, true},
+		{"I write C++ code", "C++", false, true},
+		{"Visit .NET today", ".NET", false, true},
+		{"get_user and getUser", "getUser", false, true},
+		{"getUsers", "getUser", false, false},
+		{"Café", "Caf", true, false},
+	}
+	for _, tt := range tests {
+		if got := containsWord(tt.text, tt.term, tt.fold); got != tt.want {
+			t.Errorf("containsWord(%q, %q) = %v, want %v", tt.text, tt.term, got, tt.want)
+		}
+	}
+}
+
+func TestInstructionsAndVerify(t *testing.T) {
+	g := &Glossary{
+		Terms:          map[string]map[string]string{"invoice": {"fr": "facture"}, "cloud": {"fr": "nuage"}},
+		DoNotTranslate: []string{"Go", "Acme"},
+	}
+	source := "Send the Invoice written in Go."
+
+	got := g.Instructions(source, "French")
+	want := "Use this glossary:\n- \"invoice\" -> \"facture\"\nKeep these terms exactly as written, do not translate them:\n- \"Go\"\n"
+	if got != want {
+		t.Errorf("Instructions = %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		translated string
+		want       string
+	}{
+		{"Envoyez la facture écrite en Go.", ""},
+		{"Envoyez la Facture écrite en Go.", ""},
+		{"Envoyez la note écrite en Go.", `"invoice" should be translated as "facture"`},
+		{"Envoyez la facture, c'est good.", `"Go" must not be translated`},
+	}
+	for _, tt := range tests {
+		var msgs []string
+		for _, v := range g.Verify(source, tt.translated, "fr") {
+			msgs = append(msgs, v.String())
+		}
+		if got := strings.Join(msgs, "; "); got != tt.want {
+			t.Errorf("Verify(%q) = %q, want %q", tt.translated, got, tt.want)
+		}
+	}
+
+	var none *Glossary
+	if none.Instructions(source, "fr") != "" || none.Verify(source, "x", "fr") != nil {
+		t.Error("a nil glossary adds no rules")
+	}
+}
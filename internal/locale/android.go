@@ -0,0 +1,133 @@
+package locale
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// span is the position of a value inside the original file
+type span struct {
+	key        string
+	start, end int
+	raw        bool // value contains markup and is translated as is
+}
+
+// androidDocument replaces values in place, so comments and attributes stay untouched
+type androidDocument struct {
+	data    []byte
+	spans   []span
+	entries []Entry
+}
+
+var (
+	androidStringRe = regexp.MustCompile(`(?s)<string((?:\s[^>]*)?)>(.*?)</string>`)
+	androidGroupRe  = regexp.MustCompile(`(?s)<(string-array|plurals)\b([^>]*)>(.*?)</(?:string-array|plurals)>`)
+	androidItemRe   = regexp.MustCompile(`(?s)<item\b([^>]*)>(.*?)</item>`)
+	androidNameRe   = regexp.MustCompile(`\bname\s*=\s*"([^"]*)"`)
+	androidQtyRe    = regexp.MustCompile(`\bquantity\s*=\s*"([^"]*)"`)
+)
+
+func parseAndroid(data []byte) (Document, error) {
+	if !bytes.Contains(data, []byte("<resources")) {
+		return nil, fmt.Errorf("not an Android string resource file (missing <resources>)")
+	}
+	doc := &androidDocument{data: data}
+
+	for _, m := range androidStringRe.FindAllSubmatchIndex(data, -1) {
+		attrs := string(data[m[2]:m[3]])
+		if strings.Contains(attrs, `translatable="false"`) {
+			continue
+		}
+		name := androidNameRe.FindStringSubmatch(attrs)
+		if name == nil {
+			continue
+		}
+		doc.add(name[1], m[4], m[5])
+	}
+
+	for _, m := range androidGroupRe.FindAllSubmatchIndex(data, -1) {
+		attrs := string(data[m[4]:m[5]])
+		if strings.Contains(attrs, `translatable="false"`) {
+			continue
+		}
+		name := androidNameRe.FindStringSubmatch(attrs)
+		if name == nil {
+			continue
+		}
+		body := data[m[6]:m[7]]
+		for i, item := range androidItemRe.FindAllSubmatchIndex(body, -1) {
+			key := fmt.Sprintf("%s[%d]", name[1], i)
+			if qty := androidQtyRe.FindSubmatch(body[item[2]:item[3]]); qty != nil {
+				key = fmt.Sprintf("%s[%s]", name[1], qty[1])
+			}
+			doc.add(key, m[6]+item[4], m[6]+item[5])
+		}
+	}
+
+	sort.Slice(doc.spans, func(i, j int) bool { return doc.spans[i].start < doc.spans[j].start })
+	return doc, nil
+}
+
+func (d *androidDocument) add(key string, start, end int) {
+	value := string(d.data[start:end])
+	s := span{key: key, start: start, end: end, raw: strings.Contains(value, "<")}
+	if !s.raw {
+		value = unescapeAndroid(value)
+	}
+	if strings.TrimSpace(value) == "" {
+		return
+	}
+	d.spans = append(d.spans, s)
+	d.entries = append(d.entries, Entry{Key: key, Source: value})
+}
+
+func (d *androidDocument) Entries() []Entry {
+	return d.entries
+}
+
+func (d *androidDocument) Render(translations map[string]string, from, to string) ([]byte, error) {
+	var buf bytes.Buffer
+	last := 0
+	for _, s := range d.spans {
+		t, ok := translations[s.key]
+		if !ok {
+			continue
+		}
+		buf.Write(d.data[last:s.start])
+		if s.raw {
+			buf.WriteString(t)
+		} else {
+			buf.WriteString(escapeAndroid(t))
+		}
+		last = s.end
+	}
+	buf.Write(d.data[last:])
+	return buf.Bytes(), nil
+}
+
+func unescapeAndroid(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`) && !strings.HasSuffix(s, `\"`) {
+		s = s[1 : len(s)-1]
+	}
+	r := strings.NewReplacer(
+		`\'`, `'`, `\"`, `"`, `\n`, "\n", `\t`, "\t", `\@`, `@`, `\?`, `?`, `\\`, `\`,
+		"&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'", "&amp;", "&",
+	)
+	return r.Replace(s)
+}
+
+func escapeAndroid(s string) string {
+	r := strings.NewReplacer(
+		`\`, `\\`, "\n", `\n`, "\t", `\t`, `'`, `\'`, `"`, `\"`,
+		"&", "&amp;", "<", "&lt;", ">", "&gt;",
+	)
+	s = r.Replace(s)
+	if strings.HasPrefix(s, "@") || strings.HasPrefix(s, "?") {
+		s = `\` + s
+	}
+	return s
+}
@@ -0,0 +1,187 @@
+package locale
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// jsonNode keeps the key order of the original file, which encoding/json maps would lose
+type jsonNode struct {
+	keys     []string    // object keys, in order
+	children []*jsonNode // object values or array items
+	isObject bool
+	isArray  bool
+	isString bool
+	str      string
+	raw      string // numbers, booleans and null
+}
+
+type jsonDocument struct {
+	root    *jsonNode
+	entries []Entry
+	nodes   map[string]*jsonNode
+}
+
+func parseJSON(data []byte) (Document, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	root, err := decodeJSON(dec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	doc := &jsonDocument{root: root, nodes: map[string]*jsonNode{}}
+	doc.collect(root, "")
+	return doc, nil
+}
+
+func decodeJSON(dec *json.Decoder) (*jsonNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		node := &jsonNode{isObject: t == '{', isArray: t == '['}
+		for dec.More() {
+			if node.isObject {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				key, ok := keyTok.(string)
+				if !ok {
+					return nil, fmt.Errorf("invalid object key")
+				}
+				node.keys = append(node.keys, key)
+			}
+			child, err := decodeJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			node.children = append(node.children, child)
+		}
+		if _, err := dec.Token(); err != nil { // closing delimiter
+			return nil, err
+		}
+		return node, nil
+	case string:
+		return &jsonNode{isString: true, str: t}, nil
+	case nil:
+		return &jsonNode{raw: "null"}, nil
+	case bool:
+		return &jsonNode{raw: strconv.FormatBool(t)}, nil
+	case json.Number:
+		return &jsonNode{raw: t.String()}, nil
+	}
+	return nil, io.ErrUnexpectedEOF
+}
+
+// collect lists the string values under dotted key paths (menu.file.open, items[0])
+func (d *jsonDocument) collect(node *jsonNode, path string) {
+	switch {
+	case node.isString:
+		if strings.TrimSpace(node.str) != "" {
+			d.entries = append(d.entries, Entry{Key: path, Source: node.str})
+			d.nodes[path] = node
+		}
+	case node.isObject:
+		for i, key := range node.keys {
+			child := key
+			if path != "" {
+				child = path + "." + key
+			}
+			d.collect(node.children[i], child)
+		}
+	case node.isArray:
+		for i, item := range node.children {
+			d.collect(item, fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+}
+
+func (d *jsonDocument) Entries() []Entry {
+	return d.entries
+}
+
+func (d *jsonDocument) Render(translations map[string]string, from, to string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := d.write(&buf, d.root, "", translations, 0); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+func (d *jsonDocument) write(buf *bytes.Buffer, node *jsonNode, path string, translations map[string]string, depth int) error {
+	indent := strings.Repeat("  ", depth+1)
+	switch {
+	case node.isString:
+		value := node.str
+		if t, ok := translations[path]; ok && d.nodes[path] == node {
+			value = t
+		}
+		return writeJSONString(buf, value)
+	case node.isObject:
+		if len(node.keys) == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+		buf.WriteString("{\n")
+		for i, key := range node.keys {
+			buf.WriteString(indent)
+			if err := writeJSONString(buf, key); err != nil {
+				return err
+			}
+			buf.WriteString(": ")
+			child := key
+			if path != "" {
+				child = path + "." + key
+			}
+			if err := d.write(buf, node.children[i], child, translations, depth+1); err != nil {
+				return err
+			}
+			if i < len(node.keys)-1 {
+				buf.WriteString(",")
+			}
+			buf.WriteString("\n")
+		}
+		buf.WriteString(strings.Repeat("  ", depth) + "}")
+	case node.isArray:
+		if len(node.children) == 0 {
+			buf.WriteString("[]")
+			return nil
+		}
+		buf.WriteString("[\n")
+		for i, item := range node.children {
+			buf.WriteString(indent)
+			if err := d.write(buf, item, fmt.Sprintf("%s[%d]", path, i), translations, depth+1); err != nil {
+				return err
+			}
+			if i < len(node.children)-1 {
+				buf.WriteString(",")
+			}
+			buf.WriteString("\n")
+		}
+		buf.WriteString(strings.Repeat("  ", depth) + "]")
+	default:
+		buf.WriteString(node.raw)
+	}
+	return nil
+}
+
+// writeJSONString encodes s without escaping <, > and & (common in UI strings)
+func writeJSONString(buf *bytes.Buffer, s string) error {
+	var tmp bytes.Buffer
+	enc := json.NewEncoder(&tmp)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s); err != nil {
+		return err
+	}
+	buf.Write(bytes.TrimRight(tmp.Bytes(), "\n"))
+	return nil
+}
@@ -0,0 +1,116 @@
+package locale
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Entry is a translatable string of a localization file
+type Entry struct {
+	Key    string
+	Source string
+}
+
+// Document is a parsed localization file. Only values are translated, keys and the
+// structure of the file are kept.
+type Document interface {
+	Entries() []Entry
+	// Render returns the file with the values replaced by translations. Entries
+	// missing from translations keep their source value (PO files leave them empty).
+	Render(translations map[string]string, from, to string) ([]byte, error)
+}
+
+// Parse reads a localization file, the format is chosen by the file name
+func Parse(path string, data []byte) (Document, error) {
+	name := strings.ToLower(filepath.Base(path))
+	switch ext := filepath.Ext(name); {
+	case ext == ".json":
+		return parseJSON(data)
+	case ext == ".yaml" || ext == ".yml":
+		return parseYAML(data)
+	case ext == ".po" || ext == ".pot":
+		return parsePO(data)
+	case ext == ".strings":
+		return parseStrings(data)
+	case ext == ".xml":
+		return parseAndroid(data)
+	}
+	return nil, fmt.Errorf("unsupported localization file %q (json, yaml, po, pot, strings, xml)", path)
+}
+
+// Placeholders in the common i18n syntaxes: {{count}}, {name}, {0}, %s, %1$d, %@, %(name)s, %%
+var placeholderRe = regexp.MustCompile(`\{\{[^{}]*\}\}|\{[^{}]*\}|%\([^)]+\)[sdif]|%(\d+\$)?[-+ #0]*\d*(\.\d+)?[sdifuxXeEgGcp@]|%%`)
+
+// Placeholders returns the placeholders of s, sorted
+func Placeholders(s string) []string {
+	found := placeholderRe.FindAllString(s, -1)
+	slices.Sort(found)
+	return found
+}
+
+// CheckPlaceholders fails when the translation does not keep exactly the placeholders of the source
+func CheckPlaceholders(source, translated string) error {
+	want, got := Placeholders(source), Placeholders(translated)
+	if !slices.Equal(want, got) {
+		return fmt.Errorf("placeholders changed: %v -> %v", want, got)
+	}
+	return nil
+}
+
+var langSuffixRe = regexp.MustCompile(`^(.*[._-])?([a-z]{2,3}([_-][A-Za-z]{2,4})?)$`)
+
+// languageCodes are the ISO 639 codes recognized in file names, so stems such as app or web
+// are not taken for a language
+var languageCodes = strings.Fields(`af am ar as az be bg bn bs ca cs cy da de el en eo es et eu fa fi fil fr
+	ga gl gu ha he hi hr hu hy id ig is it ja jv ka kk km kn ko ku ky la lb lo lt lv mi mk ml mn mr ms mt my
+	nb ne nl nn no or pa pl ps pt ro ru rw sd si sk sl so sq sr su sv sw ta te tg th tk tl tr tt ug uk ur uz
+	vi xh yo yue zh zu`)
+
+// knownLanguage reports whether code, with an optional region (pt-BR, en_US), is a language
+func knownLanguage(code string) bool {
+	code, _, _ = strings.Cut(strings.ReplaceAll(code, "_", "-"), "-")
+	return slices.Contains(languageCodes, strings.ToLower(code))
+}
+
+// TargetPath derives the path of the translated file from the source path:
+// en.json -> de.json, app.en.yaml -> app.de.yaml, values/strings.xml -> values-de/strings.xml,
+// en.lproj/Localizable.strings -> de.lproj/Localizable.strings, messages.pot -> de.po
+func TargetPath(path, from, to string) string {
+	dir, base := filepath.Split(path)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	to = strings.ToLower(strings.ReplaceAll(to, " ", "-"))
+	from = strings.ToLower(from)
+
+	parent := filepath.Base(filepath.Clean(dir))
+	switch {
+	case strings.EqualFold(ext, ".pot"):
+		return filepath.Join(dir, to+".po")
+	case strings.EqualFold(ext, ".xml") && (parent == "values" || strings.HasPrefix(parent, "values-")):
+		return filepath.Join(filepath.Dir(filepath.Clean(dir)), "values-"+to, base)
+	case strings.HasSuffix(parent, ".lproj"):
+		return filepath.Join(filepath.Dir(filepath.Clean(dir)), to+".lproj", base)
+	}
+
+	if m := langSuffixRe.FindStringSubmatch(stem); m != nil && (from == "" && knownLanguage(m[2]) || strings.EqualFold(m[2], from)) {
+		return filepath.Join(dir, m[1]+to+ext)
+	}
+	return filepath.Join(dir, stem+"."+to+ext)
+}
+
+// SourceLanguage guesses the language code of a file from its name, empty when unknown
+func SourceLanguage(path string) string {
+	dir, base := filepath.Split(path)
+	parent := filepath.Base(filepath.Clean(dir))
+	if strings.HasSuffix(parent, ".lproj") {
+		return strings.TrimSuffix(parent, ".lproj")
+	}
+	stem := strings.TrimSuffix(base, filepath.Ext(base))
+	if m := langSuffixRe.FindStringSubmatch(stem); m != nil && knownLanguage(m[2]) {
+		return m[2]
+	}
+	return ""
+}
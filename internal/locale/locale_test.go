@@ -0,0 +1,239 @@
+package locale
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// documents of every format, TestRender translates the Hello of key
+var documents = []struct {
+	path string
+	data string
+	key  string
+}{
+	{
+		path: "en.json",
+		data: `{
+  "greeting": "Hello",
+  "menu": {
+    "open": "Open {name}",
+    "items": [
+      "One",
+      "Two & <three>"
+    ],
+    "count": 3,
+    "enabled": true,
+    "empty": {}
+  }
+}
+`,
+		key: "greeting",
+	},
+	{
+		path: "en.yaml",
+		data: `en:
+  # the greeting on the home page
+  greeting: Hello
+  menu:
+    open: Open %{name}
+    quoted: "Yes: no"
+`,
+		key: "en.greeting",
+	},
+	{
+		path: "messages.po",
+		data: `# Translation catalog
+msgid ""
+msgstr ""
+"Project-Id-Version: app\n"
+"Language: en\n"
+
+#: main.go:10
+msgid "greeting"
+msgstr ""
+
+msgctxt "menu"
+msgid "Open"
+msgstr ""
+
+msgid "one file"
+msgid_plural "%d files"
+msgstr[0] ""
+msgstr[1] ""
+`,
+		key: "greeting",
+	},
+	{
+		path: "en.lproj/Localizable.strings",
+		data: `/* Greeting */
+"greeting" = "Hello";
+"quote" = "Say \"hi\"\n";
+"empty" = "";
+`,
+		key: "greeting",
+	},
+	{
+		path: "values/strings.xml",
+		data: `<?xml version="1.0" encoding="utf-8"?>
+<resources>
+    <!-- Greeting -->
+    <string name="greeting">Hello</string>
+    <string name="app_id" translatable="false">com.example</string>
+    <string name="styled">Tap <b>here</b></string>
+    <string-array name="planets">
+        <item>Mercury</item>
+        <item>Venus</item>
+    </string-array>
+    <plurals name="files">
+        <item quantity="one">%d file</item>
+        <item quantity="other">%d files</item>
+    </plurals>
+</resources>
+`,
+		key: "greeting",
+	},
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, tt := range documents {
+		t.Run(filepath.Base(tt.path), func(t *testing.T) {
+			doc, err := Parse(tt.path, []byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(doc.Entries()) == 0 {
+				t.Fatal("no entries")
+			}
+
+			// No translations, and translations equal to the sources, give back the file
+			out, err := doc.Render(nil, "en", "en")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != tt.data {
+				t.Errorf("Render(nil) changed the file:\n%s", out)
+			}
+			if strings.HasSuffix(tt.path, ".po") {
+				return // PO sources are msgids, the msgstrs stay empty
+			}
+			same := map[string]string{}
+			for _, e := range doc.Entries() {
+				same[e.Key] = e.Source
+			}
+			doc, _ = Parse(tt.path, []byte(tt.data))
+			if out, err = doc.Render(same, "en", "en"); err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != tt.data {
+				t.Errorf("Render(sources) changed the file:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	for _, tt := range documents {
+		t.Run(filepath.Base(tt.path), func(t *testing.T) {
+			doc, err := Parse(tt.path, []byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := doc.Render(map[string]string{tt.key: "Hallo"}, "en", "de")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := strings.Replace(tt.data, "Hello", "Hallo", 1)
+			switch {
+			case strings.HasSuffix(tt.path, ".po"):
+				want = strings.Replace(tt.data, "msgid \"greeting\"\nmsgstr \"\"", "msgid \"greeting\"\nmsgstr \"Hallo\"", 1)
+				want = strings.Replace(want, "Language: en", "Language: de", 1)
+			case strings.HasSuffix(tt.path, ".yaml"):
+				want = strings.Replace(strings.Replace(tt.data, "en:", "de:", 1), "Hello", "Hallo", 1)
+			}
+			if string(out) != want {
+				t.Errorf("got\n%s\nwant\n%s", out, want)
+			}
+		})
+	}
+}
+
+func TestEntries(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"en.json", "greeting=Hello menu.open=Open {name} menu.items[0]=One menu.items[1]=Two & <three>"},
+		{"en.yaml", "en.greeting=Hello en.menu.open=Open %{name} en.menu.quoted=Yes: no"},
+		{"messages.po", "greeting=greeting menu\x04Open=Open one file[0]=one file one file[1]=%d files"},
+		{"en.lproj/Localizable.strings", "greeting=Hello quote=Say \"hi\"\n"},
+		{"values/strings.xml", "greeting=Hello styled=Tap <b>here</b> planets[0]=Mercury planets[1]=Venus files[one]=%d file files[other]=%d files"},
+	}
+	for i, tt := range tests {
+		doc, err := Parse(tt.path, []byte(documents[i].data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, e := range doc.Entries() {
+			got = append(got, e.Key+"="+e.Source)
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("%s entries:\n%q\nwant\n%q", tt.path, strings.Join(got, " "), tt.want)
+		}
+	}
+}
+
+func TestCheckPlaceholders(t *testing.T) {
+	tests := []struct {
+		source, translated string
+		ok                 bool
+	}{
+		{"Hello {name}", "Hallo {name}", true},
+		{"{{count}} items", "{{count}} Elemente", true},
+		{"%1$s of %2$d", "%2$d von %1$s", true},
+		{"Save %(file)s", "%(file)s speichern", true},
+		{"100%% done", "100%% fertig", true},
+		{"Hello %@", "Hallo %@", true},
+		{"Hello {name}", "Hallo {nom}", false},
+		{"%s and %s", "%s und", false},
+		{"{0} files", "Dateien", false},
+	}
+	for _, tt := range tests {
+		if err := CheckPlaceholders(tt.source, tt.translated); (err == nil) != tt.ok {
+			t.Errorf("CheckPlaceholders(%q, %q) = %v", tt.source, tt.translated, err)
+		}
+	}
+}
+
+func TestTargetPath(t *testing.T) {
+	tests := []struct {
+		path, from, to, want string
+	}{
+		{"locales/en.json", "en", "de", "locales/de.json"},
+		{"config/locales/app.en.yml", "", "fr", "config/locales/app.fr.yml"},
+		{"i18n/messages_en.yaml", "en", "pt-BR", "i18n/messages_pt-br.yaml"},
+		{"res/values/strings.xml", "", "de", "res/values-de/strings.xml"},
+		{"res/values-en/strings.xml", "en", "ja", "res/values-ja/strings.xml"},
+		{"ios/en.lproj/Localizable.strings", "", "de", "ios/de.lproj/Localizable.strings"},
+		{"po/messages.pot", "", "de", "po/de.po"},
+		{"strings.json", "", "de", "strings.de.json"},
+		{"locales/app.json", "", "de", "locales/app.de.json"},
+		{"web/main.yaml", "", "fr", "web/main.fr.yaml"},
+		{"xx.json", "xx", "de", "de.json"},
+	}
+	for _, tt := range tests {
+		if got := filepath.ToSlash(TargetPath(tt.path, tt.from, tt.to)); got != tt.want {
+			t.Errorf("TargetPath(%q, %q, %q) = %q, want %q", tt.path, tt.from, tt.to, got, tt.want)
+		}
+	}
+
+	for path, want := range map[string]string{
+		"en.json": "en", "app.fr.yml": "fr", "messages_pt-BR.yaml": "pt-BR", "en.lproj/Localizable.strings": "en",
+		"strings.xml": "", "app.json": "", "main.yaml": "", "web.json": "", "app.web.json": "",
+	} {
+		if got := SourceLanguage(path); got != want {
+			t.Errorf("SourceLanguage(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package locale
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// poMessage is one entry of a gettext catalog. The comment and msgid lines are kept verbatim,
+// only the msgstr lines are rewritten.
+type poMessage struct {
+	lines   []string // everything before the first msgstr
+	context string
+	id      string
+	plural  string
+	strs    []string // msgstr, or msgstr[n] for plural messages
+	header  bool
+}
+
+type poDocument struct {
+	messages []*poMessage
+	entries  []Entry
+}
+
+var poIndexRe = regexp.MustCompile(`^msgstr\[(\d+)\]`)
+
+func parsePO(data []byte) (Document, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	doc := &poDocument{}
+
+	var msg *poMessage
+	var target *string // field that continuation lines append to
+	flush := func() {
+		if msg != nil && (len(msg.lines) > 0 || len(msg.strs) > 0) {
+			doc.messages = append(doc.messages, msg)
+		}
+		msg, target = nil, nil
+	}
+
+	for n, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			flush()
+			continue
+		}
+		if msg == nil {
+			msg = &poMessage{}
+		}
+		if strings.HasPrefix(trimmed, "#") {
+			msg.lines = append(msg.lines, line)
+			continue
+		}
+
+		keyword, rest, _ := strings.Cut(trimmed, " ")
+		if strings.HasPrefix(trimmed, `"`) {
+			keyword, rest = "", trimmed
+		}
+		value, err := strconv.Unquote(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("invalid PO string on line %d: %s", n+1, line)
+		}
+
+		switch {
+		case keyword == "":
+			if target == nil {
+				return nil, fmt.Errorf("unexpected string on line %d", n+1)
+			}
+			*target += value
+			if len(msg.strs) == 0 {
+				msg.lines = append(msg.lines, line)
+			}
+		case keyword == "msgctxt":
+			msg.context, target = value, &msg.context
+			msg.lines = append(msg.lines, line)
+		case keyword == "msgid":
+			msg.id, target = value, &msg.id
+			msg.lines = append(msg.lines, line)
+		case keyword == "msgid_plural":
+			msg.plural, target = value, &msg.plural
+			msg.lines = append(msg.lines, line)
+		case keyword == "msgstr" || poIndexRe.MatchString(keyword):
+			msg.strs = append(msg.strs, value)
+			target = &msg.strs[len(msg.strs)-1]
+		default:
+			return nil, fmt.Errorf("unknown PO keyword %q on line %d", keyword, n+1)
+		}
+	}
+	flush()
+
+	for _, m := range doc.messages {
+		if len(m.strs) == 0 {
+			continue // comment only block
+		}
+		if m.id == "" && m.context == "" {
+			m.header = true
+			continue
+		}
+		for i := range m.strs {
+			source := m.id
+			if i > 0 && m.plural != "" {
+				source = m.plural
+			}
+			doc.entries = append(doc.entries, Entry{Key: m.key(i), Source: source})
+		}
+	}
+	return doc, nil
+}
+
+// key follows the gettext convention of joining context and id with EOT
+func (m *poMessage) key(i int) string {
+	key := m.id
+	if m.context != "" {
+		key = m.context + "\x04" + key
+	}
+	if m.plural != "" {
+		key += fmt.Sprintf("[%d]", i)
+	}
+	return key
+}
+
+func (d *poDocument) Entries() []Entry {
+	return d.entries
+}
+
+func (d *poDocument) Render(translations map[string]string, from, to string) ([]byte, error) {
+	var buf bytes.Buffer
+	for i, m := range d.messages {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		for _, line := range m.lines {
+			buf.WriteString(line + "\n")
+		}
+		for j, str := range m.strs {
+			switch {
+			case m.header:
+				str = setPOLanguage(str, to)
+			default:
+				str = translations[m.key(j)]
+			}
+			keyword := "msgstr"
+			if m.plural != "" {
+				keyword = fmt.Sprintf("msgstr[%d]", j)
+			}
+			writePOString(&buf, keyword, str)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// setPOLanguage updates or adds the Language header field
+func setPOLanguage(header, lang string) string {
+	lines := strings.SplitAfter(header, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "Language:") {
+			lines[i] = "Language: " + lang + "\n"
+			return strings.Join(lines, "")
+		}
+	}
+	if header != "" && !strings.HasSuffix(header, "\n") {
+		header += "\n"
+	}
+	return header + "Language: " + lang + "\n"
+}
+
+// writePOString writes multi-line strings in the usual msgstr "" + one line per string form
+func writePOString(buf *bytes.Buffer, keyword, s string) {
+	if !strings.Contains(strings.TrimSuffix(s, "\n"), "\n") {
+		fmt.Fprintf(buf, "%s %s\n", keyword, quotePO(s))
+		return
+	}
+	fmt.Fprintf(buf, "%s \"\"\n", keyword)
+	for _, line := range strings.SplitAfter(s, "\n") {
+		if line != "" {
+			buf.WriteString(quotePO(line) + "\n")
+		}
+	}
+}
+
+func quotePO(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+	return `"` + r.Replace(s) + `"`
+}
@@ -0,0 +1,58 @@
+package locale
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// stringsDocument handles Apple .strings files ("key" = "value";), replacing values in place
+type stringsDocument struct {
+	data    []byte
+	spans   []span
+	entries []Entry
+}
+
+var appleStringRe = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"\s*=\s*"((?:[^"\\]|\\.)*)"\s*;`)
+
+func parseStrings(data []byte) (Document, error) {
+	doc := &stringsDocument{data: data}
+	for _, m := range appleStringRe.FindAllSubmatchIndex(data, -1) {
+		key := unescapeApple(string(data[m[2]:m[3]]))
+		value := unescapeApple(string(data[m[4]:m[5]]))
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		doc.spans = append(doc.spans, span{key: key, start: m[4], end: m[5]})
+		doc.entries = append(doc.entries, Entry{Key: key, Source: value})
+	}
+	return doc, nil
+}
+
+func (d *stringsDocument) Entries() []Entry {
+	return d.entries
+}
+
+func (d *stringsDocument) Render(translations map[string]string, from, to string) ([]byte, error) {
+	var buf bytes.Buffer
+	last := 0
+	for _, s := range d.spans {
+		t, ok := translations[s.key]
+		if !ok {
+			continue
+		}
+		buf.Write(d.data[last:s.start])
+		buf.WriteString(escapeApple(t))
+		last = s.end
+	}
+	buf.Write(d.data[last:])
+	return buf.Bytes(), nil
+}
+
+func unescapeApple(s string) string {
+	return strings.NewReplacer(`\"`, `"`, `\n`, "\n", `\t`, "\t", `\\`, `\`).Replace(s)
+}
+
+func escapeApple(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`).Replace(s)
+}
@@ -0,0 +1,80 @@
+package locale
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlDocument edits the parsed node tree, which keeps key order and comments
+type yamlDocument struct {
+	root    yaml.Node
+	entries []Entry
+	nodes   map[string]*yaml.Node
+}
+
+func parseYAML(data []byte) (Document, error) {
+	doc := &yamlDocument{nodes: map[string]*yaml.Node{}}
+	if err := yaml.Unmarshal(data, &doc.root); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	if len(doc.root.Content) > 0 {
+		doc.collect(doc.root.Content[0], "")
+	}
+	return doc, nil
+}
+
+func (d *yamlDocument) collect(node *yaml.Node, path string) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.Tag == "!!str" && strings.TrimSpace(node.Value) != "" {
+			d.entries = append(d.entries, Entry{Key: path, Source: node.Value})
+			d.nodes[path] = node
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if path != "" {
+				key = path + "." + key
+			}
+			d.collect(node.Content[i+1], key)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			d.collect(item, fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+}
+
+func (d *yamlDocument) Entries() []Entry {
+	return d.entries
+}
+
+func (d *yamlDocument) Render(translations map[string]string, from, to string) ([]byte, error) {
+	for key, node := range d.nodes {
+		if t, ok := translations[key]; ok {
+			node.Value = t
+		}
+	}
+
+	// Rails style files are rooted at the language code (en: ...)
+	if len(d.root.Content) > 0 {
+		top := d.root.Content[0]
+		if top.Kind == yaml.MappingNode && len(top.Content) == 2 && from != "" && strings.EqualFold(top.Content[0].Value, from) {
+			top.Content[0].Value = to
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&d.root); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,116 @@
+package markdown
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// CodeBlock is a fenced code block of an answer
+type CodeBlock struct {
+	Lang     string
+	Filename string // hint from the info string, the line above or a first line comment
+	Code     string
+}
+
+var (
+	// title="main.go", file=main.go, filename: main.go
+	infoFileRe = regexp.MustCompile(`(?:title|file|filename|name|path)\s*[=:]\s*"?([^"\s]+)"?`)
+	// a path with an extension, e.g. src/app.js or `main.go`
+	fileNameRe = regexp.MustCompile("(?:^|[\\s`*\"'(])((?:[\\w.-]+/)*[\\w-][\\w.-]*\\.[A-Za-z0-9]{1,10})(?:$|[\\s`*\"':),])")
+	// // main.go, # app.py, <!-- index.html -->, /* style.css */
+	commentFileRe = regexp.MustCompile(`^\s*(?://|#|--|<!--|/\*|;)\s*(?:(?:file|filename|path)\s*:\s*)?((?:[\w.-]+/)*[\w-][\w.-]*\.[A-Za-z0-9]{1,10})\s*(?:-->|\*/)?\s*$`)
+)
+
+// CodeBlocks returns the fenced code blocks of text, in order
+func CodeBlocks(text string) []CodeBlock {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	var blocks []CodeBlock
+	for i := 0; i < len(lines); i++ {
+		m := fenceRe.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		fence := m[1]
+		info := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(lines[i]), fence[:1]))
+		j := i + 1
+		for j < len(lines) && !isClosingFence(lines[j], fence) {
+			j++
+		}
+		code := lines[i+1 : min(j, len(lines))]
+
+		block := CodeBlock{Code: strings.Join(code, "\n")}
+		if fields := strings.Fields(info); len(fields) > 0 {
+			block.Lang = fields[0]
+			// ```go:main.go and ```main.go
+			if lang, file, ok := strings.Cut(block.Lang, ":"); ok {
+				block.Lang, block.Filename = lang, file
+			} else if strings.Contains(block.Lang, ".") {
+				block.Filename = block.Lang
+				block.Lang = strings.TrimPrefix(path.Ext(block.Lang), ".")
+			}
+		}
+		if block.Filename == "" {
+			block.Filename = filenameHint(info, lines[:i], code)
+		}
+		if len(code) > 0 && block.Filename != "" && commentFileRe.MatchString(code[0]) {
+			// the hint comment is part of the answer, not of the file
+			if commentFileRe.FindStringSubmatch(code[0])[1] == block.Filename {
+				block.Code = strings.Join(code[1:], "\n")
+			}
+		}
+		if block.Code != "" {
+			block.Code += "\n"
+		}
+		blocks = append(blocks, block)
+		i = j
+	}
+	return blocks
+}
+
+// filenameHint looks for a file name in the info string, the last non-empty line before the
+// block (e.g. "**src/app.js**" or "Create `main.go`:") and a comment on the first code line
+func filenameHint(info string, before, code []string) string {
+	if m := infoFileRe.FindStringSubmatch(info); m != nil {
+		return m[1]
+	}
+	for k := len(before) - 1; k >= 0 && k >= len(before)-2; k-- {
+		line := strings.TrimSpace(before[k])
+		if line == "" {
+			continue
+		}
+		if fenceRe.MatchString(line) {
+			break
+		}
+		if m := fileNameRe.FindAllStringSubmatch(line, -1); len(m) > 0 {
+			return m[len(m)-1][1]
+		}
+		break
+	}
+	if len(code) > 0 {
+		if m := commentFileRe.FindStringSubmatch(code[0]); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// langNames maps the names of a language to one, only true synonyms: the highlighter's aliases
+// also group related languages (ts with js) that must not match here
+var langNames = map[string]string{
+	"golang": "go", "py": "python", "python3": "python",
+	"javascript": "js", "node": "js", "typescript": "ts", "rs": "rust", "rb": "ruby",
+	"bash": "sh", "shell": "sh", "yml": "yaml", "c++": "cpp", "cs": "csharp", "kt": "kotlin", "md": "markdown",
+}
+
+// SameLang reports whether two code block languages match, accepting synonyms (js, javascript)
+func SameLang(a, b string) bool {
+	norm := func(s string) string {
+		s = strings.ToLower(s)
+		if name, ok := langNames[s]; ok {
+			return name
+		}
+		return s
+	}
+	return norm(a) == norm(b)
+}
@@ -0,0 +1,47 @@
+package markdown
+
+import "testing"
+
+func TestSameLang(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"go", "golang", true},
+		{"JS", "javascript", true},
+		{"ts", "typescript", true},
+		{"ts", "js", false},
+		{"typescript", "javascript", false},
+		{"tsx", "ts", false},
+		{"jsx", "js", false},
+		{"bash", "sh", true},
+		{"yml", "YAML", true},
+		{"kotlin", "java", false},
+		{"c", "java", false},
+		{"c", "cpp", false},
+		{"python", "py", true},
+	}
+	for _, tt := range tests {
+		if got := SameLang(tt.a, tt.b); got != tt.want {
+			t.Errorf("SameLang(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCodeBlocks(t *testing.T) {
+	answer := "Create `main.go`:\n\n```go\npackage main\n```\n\n```ts:src/app.ts\nlet x = 1\n```\n\n```js\n// lib/util.js\nexport {}\n```\n"
+	want := []CodeBlock{
+		{Lang: "go", Filename: "main.go", Code: "package main\n"},
+		{Lang: "ts", Filename: "src/app.ts", Code: "let x = 1\n"},
+		{Lang: "js", Filename: "lib/util.js", Code: "export {}\n"},
+	}
+	got := CodeBlocks(answer)
+	if len(got) != len(want) {
+		t.Fatalf("got %d blocks, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("block %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
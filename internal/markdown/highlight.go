@@ -0,0 +1,119 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+)
+
+// syntax is a minimal line based highlighter: comments, strings, numbers and keywords
+type syntax struct {
+	comment  string // line comment prefix
+	keywords map[string]bool
+}
+
+func words(s string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range strings.Fields(s) {
+		set[w] = true
+	}
+	return set
+}
+
+var syntaxes = map[string]syntax{
+	"go": {"//", words(`break case chan const continue default defer else fallthrough for func go goto if import
+		interface map package range return select struct switch type var nil true false error string int bool byte`)},
+	"python": {"#", words(`and as assert async await break class continue def del elif else except finally for from
+		global if import in is lambda None nonlocal not or pass raise return True False try while with yield self`)},
+	"js": {"//", words(`async await break case catch class const continue default delete do else export extends
+		false finally for function if import in instanceof let new null return super switch this throw true try
+		typeof undefined var void while yield interface type enum implements`)},
+	"sh": {"#", words(`if then else elif fi for while until do done case esac function in return local export
+		echo exit set unset source`)},
+	"rust": {"//", words(`as async await break const continue crate else enum extern false fn for if impl in let loop
+		match mod move mut pub ref return self Self static struct super trait true type unsafe use where while`)},
+	"java": {"//", words(`abstract boolean break case catch class const continue default do double else enum extends
+		final finally float for if implements import instanceof int interface long new null package private
+		protected public return static super switch this throw throws true false try void while var`)},
+	"sql": {"--", words(`select from where insert into values update set delete create table alter drop join left
+		right inner outer on and or not null as group by order having limit distinct union SELECT FROM WHERE INSERT
+		INTO VALUES UPDATE SET DELETE CREATE TABLE ALTER DROP JOIN LEFT RIGHT INNER OUTER ON AND OR NOT NULL AS GROUP
+		BY ORDER HAVING LIMIT DISTINCT UNION`)},
+	"yaml": {"#", words(`true false null`)},
+	"json": {"", words(`true false null`)},
+}
+
+var syntaxAliases = map[string]string{
+	"golang": "go", "py": "python", "python3": "python",
+	"javascript": "js", "ts": "js", "typescript": "js", "jsx": "js", "tsx": "js",
+	"bash": "sh", "shell": "sh", "zsh": "sh", "console": "sh",
+	"rs": "rust", "kotlin": "java", "c": "java", "cpp": "java", "c++": "java", "csharp": "java", "cs": "java",
+	"yml": "yaml",
+}
+
+var tokenPatterns = regexp.MustCompile(`"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'|\b\d+(?:\.\d+)?\b|\b[A-Za-z_][A-Za-z0-9_]*\b`)
+
+// code renders a fenced block indented, highlighted when colors are enabled
+func (s style) code(lines []string, lang string) []string {
+	lang = strings.ToLower(lang)
+	if alias, ok := syntaxAliases[lang]; ok {
+		lang = alias
+	}
+	syn, known := syntaxes[lang]
+
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		if bool(s) && known {
+			line = syn.highlight(line)
+		} else {
+			line = s.apply(green, line)
+		}
+		out[i] = "    " + line
+	}
+	return out
+}
+
+func (syn syntax) highlight(line string) string {
+	code, comment := line, ""
+	if syn.comment != "" {
+		if idx := commentIndex(line, syn.comment); idx >= 0 {
+			code, comment = line[:idx], line[idx:]
+		}
+	}
+
+	code = tokenPatterns.ReplaceAllStringFunc(code, func(tok string) string {
+		switch {
+		case strings.HasPrefix(tok, `"`) || strings.HasPrefix(tok, "'"):
+			return green + tok + reset
+		case tok[0] >= '0' && tok[0] <= '9':
+			return yellow + tok + reset
+		case syn.keywords[tok]:
+			return magenta + tok + reset
+		}
+		return tok
+	})
+	if comment != "" {
+		comment = gray + comment + reset
+	}
+	return code + comment
+}
+
+// commentIndex finds the start of a line comment outside of string literals
+func commentIndex(line, prefix string) int {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case strings.HasPrefix(line[i:], prefix):
+			return i
+		}
+	}
+	return -1
+}
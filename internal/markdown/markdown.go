@@ -0,0 +1,316 @@
+package markdown
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// segment is a piece of the document. Only prose segments are sent to the model, everything
+// else (code, front matter, HTML blocks, link definitions) is re-emitted byte for byte.
+type segment struct {
+	raw    string // verbatim text, for non-prose segments
+	prefix string // block markers kept verbatim: indentation, #, >, -, 1.
+	text   string // prose with inline code, URLs and HTML replaced by [[cN]] tokens
+	suffix string // trailing whitespace and newline
+	masks  []string
+	prose  bool
+	crlf   bool // continuation lines end in \r\n, text joins them with \n
+}
+
+// Document is a Markdown document split into prose and verbatim segments
+type Document struct {
+	segments []segment
+}
+
+// IsMarkdown reports whether the file name has a Markdown extension
+func IsMarkdown(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown", ".mdx":
+		return true
+	}
+	return false
+}
+
+var (
+	fenceRe     = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+	refDefRe    = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:\s*\S`)
+	ruleRe      = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,}|=+[ \t]*)$`)
+	tableSepRe  = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(?:\|\s*:?-+:?\s*)*\|?\s*$`)
+	htmlBlockRe = regexp.MustCompile(`^ {0,3}<(?:[A-Za-z][A-Za-z0-9-]*|/[A-Za-z]|!--)`)
+	prefixRe    = regexp.MustCompile(`^[ \t]*(?:>[ \t]?)*[ \t]*(?:#{1,6}[ \t]+|[-*+][ \t]+(?:\[[ xX]\][ \t]+)?|\d{1,9}[.)][ \t]+)?`)
+	listRe      = regexp.MustCompile(`^[ \t]*(?:[-*+]|\d{1,9}[.)])[ \t]+`)
+)
+
+// Parse splits a Markdown document. Front matter, fenced and indented code blocks, HTML blocks,
+// link reference definitions, rules and table separators are kept verbatim; headings, paragraphs,
+// list items, quotes and table rows are prose.
+func Parse(text string) *Document {
+	doc := &Document{}
+	lines := strings.SplitAfter(text, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	i := 0
+	// YAML (---) or TOML (+++) front matter
+	if len(lines) > 0 {
+		if open := strings.TrimSpace(lines[0]); open == "---" || open == "+++" {
+			for j := 1; j < len(lines); j++ {
+				if end := strings.TrimSpace(lines[j]); end == open || (open == "---" && end == "...") {
+					doc.verbatim(strings.Join(lines[:j+1], ""))
+					i = j + 1
+					break
+				}
+			}
+		}
+	}
+
+	prevBlank, inList := true, false
+	for i < len(lines) {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			doc.verbatim(line)
+			prevBlank = true
+			i++
+			continue
+
+		case fenceRe.MatchString(line):
+			fence := fenceRe.FindStringSubmatch(line)[1]
+			j := i + 1
+			for j < len(lines) && !isClosingFence(lines[j], fence) {
+				j++
+			}
+			j = min(j+1, len(lines))
+			doc.verbatim(strings.Join(lines[i:j], ""))
+			i = j
+
+		case prevBlank && !inList && (strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t")):
+			j := i + 1
+			for j < len(lines) && (strings.TrimSpace(lines[j]) == "" || strings.HasPrefix(lines[j], "    ") || strings.HasPrefix(lines[j], "\t")) {
+				j++
+			}
+			// Trailing blank lines are not part of the code block
+			for j > i+1 && strings.TrimSpace(lines[j-1]) == "" {
+				j--
+			}
+			doc.verbatim(strings.Join(lines[i:j], ""))
+			i = j
+
+		case prevBlank && htmlBlockRe.MatchString(line):
+			j := i + 1
+			for j < len(lines) && strings.TrimSpace(lines[j]) != "" {
+				j++
+			}
+			doc.verbatim(strings.Join(lines[i:j], ""))
+			i = j
+
+		case refDefRe.MatchString(line) || ruleRe.MatchString(strings.TrimRight(line, "\r\n")) || tableSepRe.MatchString(line) && strings.Contains(line, "-"):
+			doc.verbatim(line)
+			i++
+
+		default:
+			i = doc.prose(lines, i)
+			inList = listRe.MatchString(line) || inList && leadingSpace(line) != ""
+			prevBlank = false
+			continue
+		}
+		// An unindented block ends the list
+		inList = inList && leadingSpace(line) != ""
+		prevBlank = false
+	}
+	return doc
+}
+
+func isClosingFence(line, fence string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == ""
+}
+
+func (d *Document) verbatim(raw string) {
+	d.segments = append(d.segments, segment{raw: raw})
+}
+
+// prose adds the block starting at lines[i] and returns the index of the next line. Paragraph
+// continuation lines are joined into the block, headings and table rows are a single line.
+func (d *Document) prose(lines []string, i int) int {
+	first := strings.TrimRight(lines[i], "\r\n")
+	prefix := prefixRe.FindString(first)
+	body := []string{first[len(prefix):]}
+	single := strings.Contains(prefix, "#") || strings.HasPrefix(strings.TrimSpace(first), "|")
+
+	j := i + 1
+	for ; !single && j < len(lines); j++ {
+		next := strings.TrimRight(lines[j], "\r\n")
+		trimmed := strings.TrimSpace(next)
+		if trimmed == "" || prefixRe.FindString(next) != leadingSpace(next) || fenceRe.MatchString(next) ||
+			htmlBlockRe.MatchString(next) || ruleRe.MatchString(next) || strings.HasPrefix(trimmed, "|") || refDefRe.MatchString(next) {
+			break
+		}
+		body = append(body, next)
+	}
+
+	text := strings.Join(body, "\n")
+	suffix := text[len(strings.TrimRightFunc(text, unicode.IsSpace)):] + lines[j-1][len(strings.TrimRight(lines[j-1], "\r\n")):]
+	text = strings.TrimRightFunc(text, unicode.IsSpace)
+
+	masked, masks := mask(text)
+	seg := segment{prefix: prefix, text: masked, suffix: suffix, masks: masks, prose: true, crlf: strings.HasSuffix(lines[i], "\r\n")}
+	// Nothing to translate, e.g. a line with only an image or a URL
+	if strings.IndexFunc(tokenRe.ReplaceAllString(masked, ""), unicode.IsLetter) < 0 {
+		seg = segment{raw: strings.Join(lines[i:j], "")}
+	}
+	d.segments = append(d.segments, seg)
+	return j
+}
+
+func leadingSpace(s string) string {
+	return s[:len(s)-len(strings.TrimLeft(s, " \t"))]
+}
+
+var (
+	tokenRe   = regexp.MustCompile(`\[\[c(\d+)\]\]`)
+	linkRe    = regexp.MustCompile(`\]\((?:<[^>]*>|[^()\s]*(?:\([^()\s]*\)[^()\s]*)*)(?:\s+(?:"[^"]*"|'[^']*'|\([^)]*\)))?\s*\)|\]\[[^\]]*\]`)
+	inlineRe  = regexp.MustCompile(`<(?:https?://|mailto:)[^>\s]+>|</?[A-Za-z][A-Za-z0-9-]*(?:\s[^<>]*)?/?>|<!--.*?-->|https?://[^\s<>()\[\]]*[^\s<>()\[\].,;:!?'"]`)
+	entityRe  = regexp.MustCompile(`&(?:#\d+|#x[0-9A-Fa-f]+|[A-Za-z]+);`)
+	maskables = []*regexp.Regexp{linkRe, inlineRe, entityRe}
+)
+
+// mask replaces inline code, link destinations, URLs, inline HTML and entities with [[cN]] tokens
+func mask(text string) (string, []string) {
+	var masks []string
+	token := func(s string) string {
+		masks = append(masks, s)
+		return fmt.Sprintf("[[c%d]]", len(masks))
+	}
+
+	// Code spans: a run of backticks closed by a run of the same length
+	var sb strings.Builder
+	for i := 0; i < len(text); {
+		if text[i] != '`' {
+			sb.WriteByte(text[i])
+			i++
+			continue
+		}
+		n := len(text[i:]) - len(strings.TrimLeft(text[i:], "`"))
+		run := text[i : i+n]
+		end := -1
+		for k := i + n; k < len(text); {
+			idx := strings.Index(text[k:], run)
+			if idx < 0 {
+				break
+			}
+			k += idx
+			m := len(text[k:]) - len(strings.TrimLeft(text[k:], "`"))
+			if m == n {
+				end = k + n
+				break
+			}
+			k += m
+		}
+		if end < 0 {
+			sb.WriteString(run)
+			i += n
+			continue
+		}
+		sb.WriteString(token(text[i:end]))
+		i = end
+	}
+	text = sb.String()
+
+	for _, re := range maskables {
+		text = re.ReplaceAllStringFunc(text, func(m string) string {
+			if tokenRe.MatchString(m) {
+				return m
+			}
+			// Keep the closing bracket of the link text outside the token
+			if strings.HasPrefix(m, "]") {
+				return "]" + token(m[1:])
+			}
+			return token(m)
+		})
+	}
+	return text, masks
+}
+
+// Prose returns the text of the prose segments, in order. Inline code, URLs and inline HTML
+// are replaced by [[cN]] tokens that must be kept in the processed text.
+func (d *Document) Prose() []string {
+	var texts []string
+	for _, s := range d.segments {
+		if s.prose {
+			texts = append(texts, s.text)
+		}
+	}
+	return texts
+}
+
+// Verify checks that the processed text of the i-th prose segment kept every token exactly once
+func (d *Document) Verify(i int, text string) error {
+	seg := d.proseSegment(i)
+	if seg == nil {
+		return fmt.Errorf("no prose segment %d", i)
+	}
+	counts := make([]int, len(seg.masks))
+	for _, m := range tokenRe.FindAllStringSubmatch(text, -1) {
+		var n int
+		fmt.Sscan(m[1], &n)
+		if n < 1 || n > len(seg.masks) {
+			return fmt.Errorf("unknown placeholder %s", m[0])
+		}
+		counts[n-1]++
+	}
+	for n, c := range counts {
+		if c != 1 {
+			return fmt.Errorf("placeholder [[c%d]] (%s) appears %d times", n+1, seg.masks[n], c)
+		}
+	}
+	return nil
+}
+
+func (d *Document) proseSegment(i int) *segment {
+	for k := range d.segments {
+		if d.segments[k].prose {
+			if i == 0 {
+				return &d.segments[k]
+			}
+			i--
+		}
+	}
+	return nil
+}
+
+// Render reassembles the document with the processed prose texts, restoring the masked tokens.
+// A missing text keeps the original prose.
+func (d *Document) Render(texts []string) string {
+	var sb strings.Builder
+	i := 0
+	for _, s := range d.segments {
+		if !s.prose {
+			sb.WriteString(s.raw)
+			continue
+		}
+		text := s.text
+		if i < len(texts) && strings.TrimSpace(texts[i]) != "" {
+			text = strings.TrimSpace(texts[i])
+		}
+		i++
+		text = tokenRe.ReplaceAllStringFunc(text, func(m string) string {
+			var n int
+			fmt.Sscan(tokenRe.FindStringSubmatch(m)[1], &n)
+			if n < 1 || n > len(s.masks) {
+				return m
+			}
+			return s.masks[n-1]
+		})
+		if s.crlf {
+			text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\n", "\r\n")
+		}
+		sb.WriteString(s.prefix + text + s.suffix)
+	}
+	return sb.String()
+}
@@ -0,0 +1,93 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+const readme = "---\ntitle: Guide\n---\n\n# Getting `started`\n\nInstall with `go install ./...` and read the [docs](https://example.com/docs \"Docs\").\nThis line continues the paragraph &amp; ends here.  \n\n```sh\nai --help\n```\n\n    indented code\n\n- First item\n- Second <b>bold</b> item\n  continued\n\n> Quoted text\n\n<div>\nraw html\n</div>\n\n| Name | Value |\n| --- | --- |\n| key | see https://example.com/a |\n\n---\n\n[docs]: https://example.com\n![](image.png)\n"
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"readme", readme},
+		{"crlf", strings.ReplaceAll(readme, "\n", "\r\n")},
+		{"no final newline", "Just one line"},
+		{"unclosed fence", "Text\n\n```\ncode"},
+		{"empty", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := Parse(tt.text)
+			if got := doc.Render(nil); got != tt.text {
+				t.Errorf("Render(nil) = %q, want %q", got, tt.text)
+			}
+			prose := doc.Prose()
+			if got := doc.Render(prose); got != tt.text {
+				t.Errorf("Render(Prose()) = %q, want %q", got, tt.text)
+			}
+			for i, text := range prose {
+				if err := doc.Verify(i, text); err != nil {
+					t.Errorf("Verify(%d, %q): %v", i, text, err)
+				}
+			}
+		})
+	}
+}
+
+func TestProse(t *testing.T) {
+	want := []string{
+		"Getting [[c1]]",
+		"Install with [[c1]] and read the [docs][[c2]].\nThis line continues the paragraph [[c3]] ends here.",
+		"First item",
+		"Second [[c1]]bold[[c2]] item\n  continued",
+		"Quoted text",
+		"| Name | Value |",
+		"| key | see [[c1]] |",
+	}
+	got := Parse(readme).Prose()
+	if strings.Join(got, "\n--\n") != strings.Join(want, "\n--\n") {
+		t.Errorf("Prose() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestRender(t *testing.T) {
+	doc := Parse("# Hello `world`\n\nSee [the docs](https://example.com).\n\n```\nHello\n```\n")
+	got := doc.Render([]string{"Hallo [[c1]]", "  Siehe [die Doku][[c1]].  "})
+	want := "# Hallo `world`\n\nSiehe [die Doku](https://example.com).\n\n```\nHello\n```\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	doc := Parse("Run `make` then open http://localhost:8080.\n")
+	tests := []struct {
+		text string
+		ok   bool
+	}{
+		{"Führe [[c1]] aus und öffne [[c2]].", true},
+		{"Öffne [[c2]], nachdem [[c1]] lief.", true},
+		{"Führe make aus und öffne [[c2]].", false},
+		{"Führe [[c1]] [[c1]] aus und öffne [[c2]].", false},
+		{"Führe [[c1]] aus und öffne [[c3]].", false},
+	}
+	for _, tt := range tests {
+		if err := doc.Verify(0, tt.text); (err == nil) != tt.ok {
+			t.Errorf("Verify(%q) = %v", tt.text, err)
+		}
+	}
+	if err := doc.Verify(1, "text"); err == nil {
+		t.Error("Verify of a missing segment should fail")
+	}
+}
+
+func TestIsMarkdown(t *testing.T) {
+	for path, want := range map[string]bool{"README.md": true, "a.Markdown": true, "page.mdx": true, "notes.txt": false} {
+		if got := IsMarkdown(path); got != want {
+			t.Errorf("IsMarkdown(%q) = %v", path, got)
+		}
+	}
+}
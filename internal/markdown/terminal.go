@@ -0,0 +1,249 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	bold      = "\033[1m"
+	italic    = "\033[3m"
+	underline = "\033[4m"
+	strike    = "\033[9m"
+	dim       = "\033[2m"
+	magenta   = "\033[95m"
+	cyan      = "\033[96m"
+	blue      = "\033[94m"
+	yellow    = "\033[33m"
+	green     = "\033[32m"
+	gray      = "\033[90m"
+	reset     = "\033[0m"
+)
+
+var (
+	headingRe   = regexp.MustCompile(`^ {0,3}(#{1,6})[ \t]+(.*?)(?:[ \t]+#+)?[ \t]*$`)
+	quoteRe     = regexp.MustCompile(`^[ \t]*>[ \t]?(.*)$`)
+	listItemRe  = regexp.MustCompile(`^([ \t]*)([-*+]|\d{1,9}[.)])[ \t]+(?:\[([ xX])\][ \t]+)?(.*)$`)
+	codeSpanRe  = regexp.MustCompile("``[^`]+(?:`[^`]+)*``|`[^`]+`")
+	boldRe      = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	italicRe    = regexp.MustCompile(`\*([^*\s][^*]*)\*|(?:^|\b)_([^_\s][^_]*)_(?:\b|$)`)
+	strikeRe    = regexp.MustCompile(`~~([^~]+)~~`)
+	mdLinkRe    = regexp.MustCompile(`!?\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	ansiRe      = regexp.MustCompile("\033\\[[0-9;]*m")
+	tableCellRe = regexp.MustCompile(`^\s*\|?(.*?)\|?\s*$`)
+)
+
+// style applies ANSI codes, or nothing when colors are disabled
+type style bool
+
+func (s style) apply(code, text string) string {
+	if !s || text == "" {
+		return text
+	}
+	// Nested styles end with a reset, restore the outer style after them
+	return code + strings.ReplaceAll(text, reset, reset+code) + reset
+}
+
+// Terminal formats a Markdown answer for the terminal: headings, lists, quotes, tables and
+// syntax-highlighted code blocks. Without color only the layout is applied.
+func Terminal(text string, width int, color bool) string {
+	s := style(color)
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	var out []string
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		if m := fenceRe.FindStringSubmatch(line); m != nil {
+			fence := m[1]
+			lang := strings.Fields(strings.TrimLeft(trimmed, fence[:1]) + " ")
+			j := i + 1
+			for j < len(lines) && !isClosingFence(lines[j], fence) {
+				j++
+			}
+			name := ""
+			if len(lang) > 0 {
+				name = lang[0]
+			}
+			out = append(out, s.code(lines[i+1:min(j, len(lines))], name)...)
+			i = j
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "|") && i+1 < len(lines) && strings.Contains(lines[i+1], "-") && tableSepRe.MatchString(lines[i+1]) {
+			j := i + 2
+			for j < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[j]), "|") {
+				j++
+			}
+			out = append(out, s.table(lines[i], lines[i+1], lines[i+2:j])...)
+			i = j - 1
+			continue
+		}
+
+		switch {
+		case headingRe.MatchString(line):
+			m := headingRe.FindStringSubmatch(line)
+			out = append(out, s.heading(len(m[1]), s.inline(m[2]))...)
+		case ruleRe.MatchString(line) && !strings.Contains(trimmed, "="):
+			out = append(out, s.apply(gray, strings.Repeat("─", min(width, 80))))
+		case quoteRe.MatchString(line):
+			out = append(out, s.apply(gray, "│ ")+s.apply(italic, s.inline(quoteRe.FindStringSubmatch(line)[1])))
+		case listItemRe.MatchString(line):
+			m := listItemRe.FindStringSubmatch(line)
+			marker := m[2]
+			if strings.ContainsAny(marker, "-*+") {
+				marker = "•"
+			}
+			switch m[3] {
+			case " ":
+				marker += " ☐"
+			case "x", "X":
+				marker += " ☑"
+			}
+			out = append(out, m[1]+s.apply(cyan, marker)+" "+s.inline(m[4]))
+		default:
+			out = append(out, s.inline(line))
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+func (s style) heading(level int, text string) []string {
+	if s {
+		switch level {
+		case 1:
+			return []string{s.apply(bold+underline+magenta, text)}
+		case 2:
+			return []string{s.apply(bold+cyan, text)}
+		default:
+			return []string{s.apply(bold, text)}
+		}
+	}
+	// Without color the top levels are underlined
+	switch level {
+	case 1:
+		return []string{text, strings.Repeat("═", visibleWidth(text))}
+	case 2:
+		return []string{text, strings.Repeat("─", visibleWidth(text))}
+	}
+	return []string{text}
+}
+
+// inline formats code spans, emphasis and links. Code spans are left untouched.
+func (s style) inline(text string) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range codeSpanRe.FindAllStringIndex(text, -1) {
+		sb.WriteString(s.emphasis(text[last:loc[0]]))
+		code := text[loc[0]:loc[1]]
+		if s {
+			code = yellow + strings.TrimSpace(strings.Trim(code, "`")) + reset
+		}
+		sb.WriteString(code)
+		last = loc[1]
+	}
+	sb.WriteString(s.emphasis(text[last:]))
+	return sb.String()
+}
+
+func (s style) emphasis(text string) string {
+	text = mdLinkRe.ReplaceAllStringFunc(text, func(m string) string {
+		sub := mdLinkRe.FindStringSubmatch(m)
+		label, url := sub[1], sub[2]
+		if label == "" || label == url {
+			return s.apply(blue+underline, url)
+		}
+		return s.apply(blue+underline, label) + " " + s.apply(dim, "("+url+")")
+	})
+	text = boldRe.ReplaceAllStringFunc(text, func(m string) string {
+		sub := boldRe.FindStringSubmatch(m)
+		return s.apply(bold, sub[1]+sub[2])
+	})
+	text = italicRe.ReplaceAllStringFunc(text, func(m string) string {
+		sub := italicRe.FindStringSubmatch(m)
+		if sub[2] != "" {
+			return strings.Replace(m, "_"+sub[2]+"_", s.apply(italic, sub[2]), 1)
+		}
+		return s.apply(italic, sub[1])
+	})
+	return strikeRe.ReplaceAllStringFunc(text, func(m string) string {
+		return s.apply(strike, strikeRe.FindStringSubmatch(m)[1])
+	})
+}
+
+// table aligns the columns and draws the borders with box characters
+func (s style) table(header, separator string, rows []string) []string {
+	split := func(line string) []string {
+		cells := strings.Split(tableCellRe.FindStringSubmatch(line)[1], "|")
+		for i, c := range cells {
+			cells[i] = s.inline(strings.TrimSpace(c))
+		}
+		return cells
+	}
+
+	var aligns []string
+	for _, a := range strings.Split(tableCellRe.FindStringSubmatch(separator)[1], "|") {
+		a = strings.TrimSpace(a)
+		switch {
+		case strings.HasPrefix(a, ":") && strings.HasSuffix(a, ":"):
+			aligns = append(aligns, "center")
+		case strings.HasSuffix(a, ":"):
+			aligns = append(aligns, "right")
+		default:
+			aligns = append(aligns, "left")
+		}
+	}
+
+	all := [][]string{split(header)}
+	for _, row := range rows {
+		all = append(all, split(row))
+	}
+	widths := make([]int, len(aligns))
+	for _, row := range all {
+		for i, cell := range row {
+			if i < len(widths) {
+				widths[i] = max(widths[i], visibleWidth(cell))
+			}
+		}
+	}
+
+	format := func(row []string, head bool) string {
+		cells := make([]string, len(widths))
+		for i, w := range widths {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			pad := w - visibleWidth(cell)
+			switch aligns[i] {
+			case "right":
+				cell = strings.Repeat(" ", pad) + cell
+			case "center":
+				cell = strings.Repeat(" ", pad/2) + cell + strings.Repeat(" ", pad-pad/2)
+			default:
+				cell += strings.Repeat(" ", pad)
+			}
+			if head {
+				cell = s.apply(bold, cell)
+			}
+			cells[i] = cell
+		}
+		return strings.Join(cells, s.apply(gray, " │ "))
+	}
+
+	rules := make([]string, len(widths))
+	for i, w := range widths {
+		rules[i] = strings.Repeat("─", w)
+	}
+	out := []string{format(all[0], true), s.apply(gray, strings.Join(rules, "─┼─"))}
+	for _, row := range all[1:] {
+		out = append(out, format(row, false))
+	}
+	return out
+}
+
+func visibleWidth(s string) int {
+	return utf8.RuneCountInString(ansiRe.ReplaceAllString(s, ""))
+}
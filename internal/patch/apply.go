@@ -0,0 +1,180 @@
+package patch
+
+import (
+	"ai/internal/diff"
+	"strings"
+)
+
+// Result of applying the hunks of a file
+type Result struct {
+	Content  string
+	Applied  int
+	Merged   int // hunks applied with a three-way merge because their context did not match
+	Rejected []Hunk
+}
+
+// Apply applies hunks to content. A hunk whose context is not found exactly (or ignoring
+// whitespace) is three-way merged into the most similar region of the file, and rejected
+// when the merge conflicts.
+func Apply(content string, hunks []Hunk) Result {
+	lines := splitLines(content)
+	var res Result
+	delta := 0 // lines added by the previous hunks
+
+	for _, h := range hunks {
+		old, repl := h.old(), h.new()
+		expected := max(h.OldStart-1, 0) + delta
+		if len(old) == 0 {
+			// Pure insertion, e.g. appending to a file
+			at := min(max(h.OldStart, 0)+delta, len(lines))
+			lines = splice(lines, at, 0, repl)
+			delta += len(repl)
+			res.Applied++
+			continue
+		}
+
+		if at, ok := find(lines, old, expected, exact); ok {
+			lines = splice(lines, at, len(old), repl)
+			delta = at - max(h.OldStart-1, 0) + len(repl) - len(old)
+			res.Applied++
+			continue
+		}
+
+		// Same lines with different whitespace, keep the indentation of the file
+		if at, ok := find(lines, old, expected, loose); ok {
+			fixed := reindent(lines[at:at+len(old)], h.Lines)
+			lines = splice(lines, at, len(old), fixed)
+			delta = at - max(h.OldStart-1, 0) + len(fixed) - len(old)
+			res.Applied++
+			res.Merged++
+			continue
+		}
+
+		if at, ok := similar(lines, old, expected); ok {
+			region := lines[at : at+len(old)]
+			merged, clean := diff.Merge3(strings.Join(old, ""), strings.Join(region, ""), strings.Join(repl, ""))
+			if clean {
+				mergedLines := splitLines(merged)
+				lines = splice(lines, at, len(old), mergedLines)
+				delta = at - max(h.OldStart-1, 0) + len(mergedLines) - len(old)
+				res.Applied++
+				res.Merged++
+				continue
+			}
+		}
+		res.Rejected = append(res.Rejected, h)
+	}
+
+	res.Content = strings.Join(lines, "")
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		res.Content = strings.TrimSuffix(res.Content, "\n")
+	}
+	return res
+}
+
+// reindent applies the hunk to a region that matches it ignoring whitespace. Context lines are
+// kept from the file and added lines take the indentation the file uses for the hunk's indentation.
+func reindent(region []string, hunk []string) []string {
+	var out []string
+	k := 0
+	hunkIndent, fileIndent := "", ""
+	for _, l := range hunk {
+		text := l[1:]
+		switch l[0] {
+		case ' ', '-':
+			hunkIndent, fileIndent = indentOf(text), indentOf(region[k])
+			if l[0] == ' ' {
+				out = append(out, region[k])
+			}
+			k++
+		case '+':
+			if strings.HasPrefix(text, hunkIndent) {
+				text = fileIndent + text[len(hunkIndent):]
+			}
+			out = append(out, text+"\n")
+		}
+	}
+	return out
+}
+
+func indentOf(s string) string {
+	return s[:len(s)-len(strings.TrimLeft(s, " \t"))]
+}
+
+func exact(a, b string) bool {
+	return a == b
+}
+
+func loose(a, b string) bool {
+	return strings.Join(strings.Fields(a), " ") == strings.Join(strings.Fields(b), " ")
+}
+
+// find returns the position of block in lines closest to expected
+func find(lines, block []string, expected int, equal func(a, b string) bool) (int, bool) {
+	matches := func(at int) bool {
+		for i, l := range block {
+			if !equal(lines[at+i], l) {
+				return false
+			}
+		}
+		return true
+	}
+	last := len(lines) - len(block)
+	// Models get line numbers wrong, start from the nearest position that exists
+	expected = max(min(expected, last), 0)
+	for d := 0; d <= len(lines); d++ {
+		if at := expected - d; at >= 0 && at <= last && matches(at) {
+			return at, true
+		}
+		if at := expected + d; d > 0 && at >= 0 && at <= last && matches(at) {
+			return at, true
+		}
+	}
+	return 0, false
+}
+
+// similar finds the region sharing the most lines with block, at least half of them
+func similar(lines, block []string, expected int) (int, bool) {
+	best, bestScore := 0, 0
+	text := strings.Join(block, "")
+	for at := 0; at+len(block) <= len(lines); at++ {
+		score := 0
+		for _, e := range diff.Lines(text, strings.Join(lines[at:at+len(block)], "")) {
+			if e.Op == diff.Equal {
+				score += strings.Count(e.Text, "\n")
+			}
+		}
+		if score > bestScore || score == bestScore && abs(at-expected) < abs(best-expected) {
+			best, bestScore = at, score
+		}
+	}
+	return best, bestScore > 0 && bestScore*2 >= len(block)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func splice(lines []string, at, remove int, insert []string) []string {
+	out := append([]string{}, lines[:at]...)
+	out = append(out, insert...)
+	return append(out, lines[at+remove:]...)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	// The last line may lack a newline, compare it like the others
+	if last := lines[len(lines)-1]; !strings.HasSuffix(last, "\n") {
+		lines[len(lines)-1] = last + "\n"
+	}
+	return lines
+}
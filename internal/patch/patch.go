@@ -0,0 +1,172 @@
+package patch
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Hunk is a @@ section of a unified diff. Lines keep their ' ', '-' or '+' prefix.
+type Hunk struct {
+	OldStart int
+	NewStart int
+	Lines    []string
+}
+
+// File is the diff of one file, a path is empty for /dev/null (created or deleted files)
+type File struct {
+	OldPath string
+	NewPath string
+	Hunks   []Hunk
+}
+
+// Path is the file the diff applies to
+func (f File) Path() string {
+	if f.NewPath != "" {
+		return f.NewPath
+	}
+	return f.OldPath
+}
+
+func (f File) IsNew() bool {
+	return f.OldPath == ""
+}
+
+func (f File) IsDelete() bool {
+	return f.NewPath == ""
+}
+
+// old returns the lines the hunk expects in the file, new the lines it replaces them with
+func (h Hunk) old() []string {
+	return h.side('+')
+}
+
+func (h Hunk) new() []string {
+	return h.side('-')
+}
+
+func (h Hunk) side(skip byte) []string {
+	var out []string
+	for _, l := range h.Lines {
+		if l[0] != skip {
+			out = append(out, l[1:]+"\n")
+		}
+	}
+	return out
+}
+
+func (h Hunk) String() string {
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@\n%s\n", h.OldStart, len(h.old()), h.NewStart, len(h.new()), strings.Join(h.Lines, "\n"))
+}
+
+// Source is an input file, labeled with the path the diff must use
+type Source struct {
+	Path    string
+	Content string
+}
+
+// Prompt asks for a unified diff of the files for the given instructions
+func Prompt(template, instructions string, files []Source) string {
+	var sb strings.Builder
+	sb.WriteString(template + "\n" + instructions + "\n\n")
+	for _, f := range files {
+		fmt.Fprintf(&sb, "--- BEGIN FILE: %s ---\n%s\n--- END FILE: %s ---\n", f.Path, strings.TrimRight(f.Content, "\n"), f.Path)
+	}
+	return sb.String()
+}
+
+var (
+	hunkRe  = regexp.MustCompile(`^@@ -(\d+)(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+	fenceRe = regexp.MustCompile("(?s)```(?:diff|patch)?[ \\t]*\\n(.*?)```")
+)
+
+// Extract returns the diff of a model reply, taking it out of Markdown fences when needed
+func Extract(reply string) string {
+	var parts []string
+	for _, m := range fenceRe.FindAllStringSubmatch(reply, -1) {
+		if strings.Contains(m[1], "\n@@ ") {
+			parts = append(parts, m[1])
+		}
+	}
+	if len(parts) == 0 {
+		return reply
+	}
+	return strings.Join(parts, "\n")
+}
+
+// Parse reads a unified diff. Line counts of the hunk headers are not trusted, models often get
+// them wrong, hunks end at the next header instead.
+func Parse(text string) ([]File, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	var files []File
+	var file *File
+	var hunk *Hunk
+
+	endHunk := func() {
+		if hunk == nil {
+			return
+		}
+		// blank lines after the last change are not part of the hunk
+		for len(hunk.Lines) > 0 && strings.TrimSpace(hunk.Lines[len(hunk.Lines)-1]) == "" {
+			hunk.Lines = hunk.Lines[:len(hunk.Lines)-1]
+		}
+		if len(hunk.Lines) > 0 {
+			file.Hunks = append(file.Hunks, *hunk)
+		}
+		hunk = nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			endHunk()
+			files = append(files, File{OldPath: diffPath(line[4:]), NewPath: diffPath(lines[i+1][4:])})
+			file = &files[len(files)-1]
+			i++
+		case hunkRe.MatchString(line):
+			if file == nil {
+				return nil, fmt.Errorf("hunk before any file header on line %d", i+1)
+			}
+			endHunk()
+			m := hunkRe.FindStringSubmatch(line)
+			oldStart, _ := strconv.Atoi(m[1])
+			newStart, _ := strconv.Atoi(m[2])
+			hunk = &Hunk{OldStart: oldStart, NewStart: newStart}
+		case hunk != nil && line != "" && strings.ContainsRune(" +-", rune(line[0])):
+			hunk.Lines = append(hunk.Lines, line)
+		case hunk != nil && line == "":
+			// Models often drop the space of empty context lines
+			hunk.Lines = append(hunk.Lines, " ")
+		case hunk != nil && strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file"
+		default:
+			endHunk()
+		}
+	}
+	endHunk()
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no unified diff found in the answer")
+	}
+	for _, f := range files {
+		if f.Path() == "" {
+			return nil, fmt.Errorf("diff without a file path")
+		}
+	}
+	return files, nil
+}
+
+// diffPath strips the a/ and b/ prefixes and a trailing timestamp, /dev/null becomes empty
+func diffPath(s string) string {
+	s, _, _ = strings.Cut(s, "\t")
+	s = strings.TrimSpace(s)
+	if s == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(s, "a/") || strings.HasPrefix(s, "b/") {
+		s = s[2:]
+	}
+	return s
+}
@@ -0,0 +1,129 @@
+package patch
+
+import (
+	"strings"
+	"testing"
+)
+
+const reply = "Here is the change:\n\n```diff\n--- a/main.go\n+++ b/main.go\n@@ -1,3 +1,3 @@\n package main\n-var x = 1\n+var x = 2\n \n@@ -10,2 +10,3 @@ func main() {\n \tprintln(x)\n+\tprintln(\"done\")\n }\n```\n\n```diff\n--- /dev/null\n+++ b/NEW.md\n@@ -0,0 +1,2 @@\n+# New\n+file\n--- a/old.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-gone\n```\n"
+
+func TestParse(t *testing.T) {
+	files, err := Parse(Extract(reply))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range files {
+		kind := "edit"
+		switch {
+		case f.IsNew():
+			kind = "new"
+		case f.IsDelete():
+			kind = "delete"
+		}
+		got = append(got, f.Path()+":"+kind)
+		for _, h := range f.Hunks {
+			got = append(got, strings.Join(h.Lines, "|"))
+		}
+	}
+	want := []string{
+		"main.go:edit",
+		" package main|-var x = 1|+var x = 2",
+		" \tprintln(x)|+\tprintln(\"done\")| }",
+		"NEW.md:new",
+		"+# New|+file",
+		"old.txt:delete",
+		"-gone",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+
+	for _, bad := range []string{"no diff here", "@@ -1 +1 @@\n-a\n+b\n", "--- /dev/null\n+++ /dev/null\n@@ -0,0 +1 @@\n+a\n"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) should fail", bad)
+		}
+	}
+}
+
+func hunk(oldStart int, lines ...string) Hunk {
+	return Hunk{OldStart: oldStart, NewStart: oldStart, Lines: lines}
+}
+
+func TestApply(t *testing.T) {
+	content := "package main\n\nfunc main() {\n\tx := 1\n\tprintln(x)\n}\n"
+	tests := []struct {
+		name    string
+		content string
+		hunks   []Hunk
+		want    string
+		merged  int
+		rejects int
+	}{
+		{
+			name:  "exact",
+			hunks: []Hunk{hunk(4, " \tx := 1", "-\tprintln(x)", "+\tprintln(x + 1)", " }")},
+			want:  "package main\n\nfunc main() {\n\tx := 1\n\tprintln(x + 1)\n}\n",
+		},
+		{
+			name:  "wrong line numbers",
+			hunks: []Hunk{hunk(40, " \tx := 1", "-\tprintln(x)", "+\tprintln(x + 1)", " }")},
+			want:  "package main\n\nfunc main() {\n\tx := 1\n\tprintln(x + 1)\n}\n",
+		},
+		{
+			name:   "different indentation",
+			hunks:  []Hunk{hunk(4, "     x := 1", "+    x++", "     println(x)")},
+			want:   "package main\n\nfunc main() {\n\tx := 1\n\tx++\n\tprintln(x)\n}\n",
+			merged: 1,
+		},
+		{
+			name:   "stale context is merged",
+			hunks:  []Hunk{hunk(1, " package mine", " ", " func main() {", " \tx := 1", "-\tprintln(x)", "+\tfmt.Println(x)")},
+			want:   "package main\n\nfunc main() {\n\tx := 1\n\tfmt.Println(x)\n}\n",
+			merged: 1,
+		},
+		{
+			name:    "context not found",
+			hunks:   []Hunk{hunk(1, " import \"os\"", "-var a = os.Args", "+var b = os.Args")},
+			want:    content,
+			rejects: 1,
+		},
+		{
+			name:  "two hunks shift lines",
+			hunks: []Hunk{hunk(1, " package main", "+// comment"), hunk(5, " \tprintln(x)", "+\tprintln(\"end\")")},
+			want:  "package main\n// comment\n\nfunc main() {\n\tx := 1\n\tprintln(x)\n\tprintln(\"end\")\n}\n",
+		},
+		{
+			name:    "new file",
+			content: "-",
+			hunks:   []Hunk{hunk(0, "+# New", "+file")},
+			want:    "# New\nfile\n",
+		},
+		{
+			name:    "no final newline",
+			content: "a\nb",
+			hunks:   []Hunk{hunk(1, " a", "-b", "+c")},
+			want:    "a\nc",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := content
+			if tt.content == "-" {
+				input = ""
+			} else if tt.content != "" {
+				input = tt.content
+			}
+			res := Apply(input, tt.hunks)
+			if res.Content != tt.want {
+				t.Errorf("got\n%q\nwant\n%q", res.Content, tt.want)
+			}
+			if res.Merged != tt.merged || len(res.Rejected) != tt.rejects {
+				t.Errorf("merged %d, rejected %d, want %d and %d", res.Merged, len(res.Rejected), tt.merged, tt.rejects)
+			}
+			if res.Applied != len(tt.hunks)-tt.rejects {
+				t.Errorf("applied %d hunks", res.Applied)
+			}
+		})
+	}
+}
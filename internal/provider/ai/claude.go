@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"time"
 )
 
 type ClaudeProvider struct {
@@ -21,12 +23,15 @@ func NewClaude(apiKey string, model string, cfg *config.Config) (*ClaudeProvider
 	if apiKey == "" {
 		return nil, fmt.Errorf("missing CLAUDE_API_KEY environment variable")
 	}
-	return &ClaudeProvider{
-		baseProvider: baseProvider{cfg: cfg},
+	p := &ClaudeProvider{
+		baseProvider: newBaseProvider("claude", cfg),
 		apiKey:       apiKey,
 		model:        model,
 		client:       &http.Client{},
-	}, nil
+	}
+	// max_tokens caps the answer, no more output has to be reserved from the rate limit
+	p.maxOutput = cfg.Claude.MaxTokens
+	return p, nil
 }
 
 func (p *ClaudeProvider) Rewrite(ctx context.Context, input string) (string, error) {
@@ -38,21 +43,60 @@ func (p *ClaudeProvider) Translate(ctx context.Context, input string, toLanguage
 }
 
 func (p *ClaudeProvider) Summarize(ctx context.Context, input string) (string, error) {
-	return p.sendRequest(ctx, p.buildPromptSummarize(input))
+	return p.summarize(ctx, input, p.sendRequest)
 }
 
 func (p *ClaudeProvider) General(ctx context.Context, input string) (string, error) {
 	return p.sendRequest(ctx, input)
 }
 
+func (p *ClaudeProvider) Check(ctx context.Context, input string) (string, error) {
+	return p.sendRequest(ctx, p.buildPromptCheck(input))
+}
+
+func (p *ClaudeProvider) Explain(ctx context.Context, input string) (string, error) {
+	return p.sendRequest(ctx, p.buildPromptExplain(input))
+}
+
+func (p *ClaudeProvider) DetectLanguage(ctx context.Context, input string) (string, error) {
+	res, err := p.sendRequest(ctx, p.buildPromptDetectLanguage(input))
+	return strings.TrimSpace(res), err
+}
+
 type message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string         `json:"role"`
+	Content []contentBlock `json:"content"`
+}
+
+// contentBlock is a text, image, tool use or tool result block of a message
+type contentBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	Source    *imageSource    `json:"source,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+type imageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
 }
 type claudeRequest struct {
-	Model     string    `json:"model"`
-	Messages  []message `json:"messages"`
-	MaxTokens int       `json:"max_tokens"`
+	Model     string       `json:"model"`
+	Messages  []message    `json:"messages"`
+	MaxTokens int          `json:"max_tokens"`
+	Tools     []claudeTool `json:"tools,omitempty"`
+}
+
+type claudeTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"input_schema"`
 }
 
 type claudeResponse struct {
@@ -68,8 +112,11 @@ type claudeResponse struct {
 
 // Content represents a content block in the response
 type messageContent struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+	Type  string          `json:"type"`
+	Text  string          `json:"text"`
+	ID    string          `json:"id"`    // tool_use blocks
+	Name  string          `json:"name"`  // tool_use blocks
+	Input json.RawMessage `json:"input"` // tool_use blocks
 }
 
 type usage struct {
@@ -78,53 +125,133 @@ type usage struct {
 }
 
 func (p *ClaudeProvider) sendRequest(ctx context.Context, prompt string) (string, error) {
-	url := p.cfg.BaseEndpoints.Claude
+	ctx, err := p.wait(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+
+	var blocks []contentBlock
+	for _, img := range p.images {
+		blocks = append(blocks, contentBlock{
+			Type: "image",
+			Source: &imageSource{
+				Type:      "base64",
+				MediaType: img.MimeType,
+				Data:      img.Base64(),
+			},
+		})
+	}
+	blocks = append(blocks, contentBlock{Type: "text", Text: prompt})
 
 	payload := claudeRequest{
 		Model: p.model,
 		Messages: []message{
 			{
 				Role:    "user",
-				Content: prompt,
+				Content: blocks,
 			},
 		},
 		MaxTokens: p.cfg.Claude.MaxTokens,
 	}
 
+	result, err := p.post(ctx, payload)
+	if err != nil {
+		return "", err
+	}
+	if len(result.Content) == 0 {
+		return "", fmt.Errorf("no content in response")
+	}
+
+	return result.Content[0].Text, nil
+}
+
+// Chat maps the conversation to messages with tool_use and tool_result blocks
+func (p *ClaudeProvider) Chat(ctx context.Context, messages []ChatMessage, tools []Tool) (ChatMessage, error) {
+	ctx, err := p.wait(ctx, chatText(messages))
+	if err != nil {
+		return ChatMessage{}, err
+	}
+
+	payload := claudeRequest{Model: p.model, MaxTokens: p.cfg.Claude.MaxTokens}
+	for _, t := range tools {
+		payload.Tools = append(payload.Tools, claudeTool{Name: t.Name, Description: t.Description, InputSchema: t.Parameters})
+	}
+	for _, m := range messages {
+		var blocks []contentBlock
+		for _, r := range m.Results {
+			blocks = append(blocks, contentBlock{Type: "tool_result", ToolUseID: r.CallID, Content: r.Output, IsError: r.IsError})
+		}
+		if m.Text != "" {
+			blocks = append(blocks, contentBlock{Type: "text", Text: m.Text})
+		}
+		for _, c := range m.Calls {
+			blocks = append(blocks, contentBlock{Type: "tool_use", ID: c.ID, Name: c.Name, Input: rawArgs(c.Args)})
+		}
+		payload.Messages = append(payload.Messages, message{Role: m.Role, Content: blocks})
+	}
+
+	result, err := p.post(ctx, payload)
+	if err != nil {
+		return ChatMessage{}, err
+	}
+	reply := ChatMessage{Role: RoleAssistant}
+	var texts []string
+	for _, c := range result.Content {
+		switch c.Type {
+		case "text":
+			texts = append(texts, c.Text)
+		case "tool_use":
+			reply.Calls = append(reply.Calls, ToolCall{ID: c.ID, Name: c.Name, Args: c.Input})
+		}
+	}
+	reply.Text = strings.Join(texts, "\n")
+	return reply, nil
+}
+
+// post sends the request to the Messages API and records its usage
+func (p *ClaudeProvider) post(ctx context.Context, payload claudeRequest) (*claudeResponse, error) {
+	url := p.cfg.BaseEndpoints.Claude
+
 	jsonBytes, err := json.Marshal(payload)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	// Create HTTP Request with Context
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBytes))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("anthropic-version", p.cfg.Claude.APIVersion)
 	req.Header.Set("x-api-key", p.apiKey)
 
+	start := time.Now()
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("API call failed: %w", err)
+		return nil, fmt.Errorf("API call failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("API error %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(bodyBytes))
 	}
 
 	var result claudeResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	if len(result.Content) == 0 {
-		return "", fmt.Errorf("no content in response")
-	}
+	recordUsage(ctx, Usage{
+		Provider:     "claude",
+		Model:        p.model,
+		InputTokens:  result.Usage.InputTokens,
+		OutputTokens: result.Usage.OutputTokens,
+		FinishReason: result.StopReason,
+		Latency:      time.Since(start),
+	})
 
-	return result.Content[0].Text, nil
+	return &result, nil
 }
@@ -9,6 +9,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"time"
 )
 
 type GeminiProvider struct {
@@ -24,7 +26,7 @@ func NewGemini(apiKey string, model string, cfg *config.Config) (*GeminiProvider
 	}
 	// Create a new GeminiProvider and return its address
 	return &GeminiProvider{
-		baseProvider: baseProvider{cfg: cfg},
+		baseProvider: newBaseProvider("gemini", cfg),
 		apiKey:       apiKey,
 		model:        model,
 		client:       &http.Client{},
@@ -40,87 +42,234 @@ func (p *GeminiProvider) Translate(ctx context.Context, input string, toLanguage
 }
 
 func (p *GeminiProvider) Summarize(ctx context.Context, input string) (string, error) {
-	return p.sendRequest(ctx, p.buildPromptSummarize(input))
+	return p.summarize(ctx, input, p.sendRequest)
 }
 
 func (p *GeminiProvider) General(ctx context.Context, text string) (string, error) {
 	return p.sendRequest(ctx, text)
 }
 
+func (p *GeminiProvider) Check(ctx context.Context, input string) (string, error) {
+	return p.sendRequest(ctx, p.buildPromptCheck(input))
+}
+
+func (p *GeminiProvider) Explain(ctx context.Context, input string) (string, error) {
+	return p.sendRequest(ctx, p.buildPromptExplain(input))
+}
+
+func (p *GeminiProvider) DetectLanguage(ctx context.Context, input string) (string, error) {
+	res, err := p.sendRequest(ctx, p.buildPromptDetectLanguage(input))
+	return strings.TrimSpace(res), err
+}
+
 type geminiRequest struct {
-	Contents []content `json:"contents"`
+	Contents []content    `json:"contents"`
+	Tools    []geminiTool `json:"tools,omitempty"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []geminiFunction `json:"functionDeclarations"`
+}
+
+type geminiFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters"`
 }
 
 type content struct {
+	Role  string `json:"role,omitempty"` // "user" or "model"
 	Parts []part `json:"parts"`
 }
 
 type part struct {
-	Text string `json:"text"`
+	Text             string            `json:"text,omitempty"`
+	InlineData       *inlineData       `json:"inline_data,omitempty"`
+	FunctionCall     *functionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *functionResponse `json:"functionResponse,omitempty"`
+	ThoughtSignature string            `json:"thoughtSignature,omitempty"`
+}
+
+type functionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type functionResponse struct {
+	ID       string         `json:"id,omitempty"`
+	Name     string         `json:"name"`
+	Response map[string]any `json:"response"`
+}
+
+type inlineData struct {
+	MimeType string `json:"mime_type"`
+	Data     string `json:"data"`
 }
 
 // responseBody matches Gemini's response structure
 type geminiResponse struct {
 	Candidates []struct {
-		Content content `json:"content"`
+		Content      content `json:"content"`
+		FinishReason string  `json:"finishReason"`
 	} `json:"candidates"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+	} `json:"usageMetadata"`
 }
 
 func (p *GeminiProvider) sendRequest(ctx context.Context, prompt string) (string, error) {
-	// Endpoint construction
-	// Example: https://generativelanguage.googleapis.com/v1beta/models/gemini-pro:generateContent
-	url := fmt.Sprintf(
-		p.cfg.BaseEndpoints.Gemini+"%s:generateContent",
-		p.model,
-	)
+	ctx, err := p.wait(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
 
 	// Prepare JSON payload
+	parts := []part{{Text: prompt}}
+	for _, img := range p.images {
+		parts = append(parts, part{InlineData: &inlineData{MimeType: img.MimeType, Data: img.Base64()}})
+	}
 	payload := geminiRequest{
 		Contents: []content{
 			{
-				Parts: []part{
-					{Text: prompt},
-				},
+				Parts: parts,
 			},
 		},
 	}
+	result, err := p.post(ctx, payload)
+	if err != nil {
+		return "", err
+	}
+	return result.Candidates[0].Content.Parts[0].Text, nil
+}
+
+// Chat maps the conversation to contents with functionCall and functionResponse parts
+func (p *GeminiProvider) Chat(ctx context.Context, messages []ChatMessage, tools []Tool) (ChatMessage, error) {
+	ctx, err := p.wait(ctx, chatText(messages))
+	if err != nil {
+		return ChatMessage{}, err
+	}
+
+	var payload geminiRequest
+	if len(tools) > 0 {
+		var decls []geminiFunction
+		for _, t := range tools {
+			decls = append(decls, geminiFunction{Name: t.Name, Description: t.Description, Parameters: t.Parameters})
+		}
+		payload.Tools = []geminiTool{{FunctionDeclarations: decls}}
+	}
+	for _, m := range messages {
+		c := content{Role: "user"}
+		if m.Role == RoleAssistant {
+			c.Role = "model"
+		}
+		for _, r := range m.Results {
+			response := map[string]any{"output": r.Output}
+			if r.IsError {
+				response = map[string]any{"error": r.Output}
+			}
+			c.Parts = append(c.Parts, part{FunctionResponse: &functionResponse{ID: geminiCallID(r.CallID), Name: r.Name, Response: response}})
+		}
+		if m.Text != "" {
+			c.Parts = append(c.Parts, part{Text: m.Text})
+		}
+		for _, call := range m.Calls {
+			c.Parts = append(c.Parts, part{FunctionCall: &functionCall{ID: geminiCallID(call.ID), Name: call.Name, Args: rawArgs(call.Args)}, ThoughtSignature: call.Signature})
+		}
+		payload.Contents = append(payload.Contents, c)
+	}
+
+	result, err := p.post(ctx, payload)
+	if err != nil {
+		return ChatMessage{}, err
+	}
+	reply := ChatMessage{Role: RoleAssistant}
+	var texts []string
+	for i, pt := range result.Candidates[0].Content.Parts {
+		switch {
+		case pt.FunctionCall != nil:
+			// Older models send no call id, the results are matched by name and order
+			id := pt.FunctionCall.ID
+			if id == "" {
+				id = fmt.Sprintf("%s%s-%d", geminiLocalID, pt.FunctionCall.Name, i)
+			}
+			reply.Calls = append(reply.Calls, ToolCall{ID: id, Name: pt.FunctionCall.Name, Args: pt.FunctionCall.Args, Signature: pt.ThoughtSignature})
+		case pt.Text != "":
+			texts = append(texts, pt.Text)
+		}
+	}
+	reply.Text = strings.Join(texts, "\n")
+	return reply, nil
+}
+
+// geminiLocalID prefixes the ids made up for calls without one, they are not sent back
+const geminiLocalID = "local:"
+
+func geminiCallID(id string) string {
+	if strings.HasPrefix(id, geminiLocalID) {
+		return ""
+	}
+	return id
+}
+
+// post sends the request to the generateContent API and records its usage
+func (p *GeminiProvider) post(ctx context.Context, payload geminiRequest) (*geminiResponse, error) {
+	// Endpoint construction
+	// Example: https://generativelanguage.googleapis.com/v1beta/models/gemini-pro:generateContent
+	url := fmt.Sprintf(
+		p.cfg.BaseEndpoints.Gemini+"%s:generateContent",
+		p.model,
+	)
+
 	jsonBytes, err := json.Marshal(payload)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	// Create HTTP Request with Context
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBytes))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	req.Header.Set("x-goog-api-key", p.apiKey)
 
 	// Execute
+	start := time.Now()
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("API call failed: %w", err)
+		return nil, fmt.Errorf("API call failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("API error %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(bodyBytes))
 	}
 
 	// Parse Response
 	var result geminiResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	// Extract text safely
+	usage := Usage{
+		Provider:     "gemini",
+		Model:        p.model,
+		InputTokens:  result.UsageMetadata.PromptTokenCount,
+		OutputTokens: result.UsageMetadata.CandidatesTokenCount,
+		Latency:      time.Since(start),
+	}
+	if len(result.Candidates) > 0 {
+		usage.FinishReason = result.Candidates[0].FinishReason
+	}
+	recordUsage(ctx, usage)
+
 	if len(result.Candidates) == 0 ||
 		len(result.Candidates[0].Content.Parts) == 0 {
-		return "", errors.New("no response content from Gemini")
+		return nil, errors.New("no response content from Gemini")
 	}
-
-	return result.Candidates[0].Content.Parts[0].Text, nil
+	return &result, nil
 }
@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"time"
 )
 
 type OllamaProvider struct {
@@ -18,7 +20,7 @@ type OllamaProvider struct {
 
 func NewOllama(model string, cfg *config.Config) (*OllamaProvider, error) {
 	return &OllamaProvider{
-		baseProvider: baseProvider{cfg: cfg},
+		baseProvider: newBaseProvider("ollama", cfg),
 		model:        model,
 		client:       &http.Client{},
 	}, nil
@@ -33,64 +35,193 @@ func (p *OllamaProvider) Translate(ctx context.Context, input string, toLanguage
 }
 
 func (p *OllamaProvider) Summarize(ctx context.Context, input string) (string, error) {
-	return p.sendRequest(ctx, p.buildPromptSummarize(input))
+	return p.summarize(ctx, input, p.sendRequest)
 }
 
 func (p *OllamaProvider) General(ctx context.Context, input string) (string, error) {
 	return p.sendRequest(ctx, input)
 }
 
+func (p *OllamaProvider) Check(ctx context.Context, input string) (string, error) {
+	return p.sendRequest(ctx, p.buildPromptCheck(input))
+}
+
+func (p *OllamaProvider) Explain(ctx context.Context, input string) (string, error) {
+	return p.sendRequest(ctx, p.buildPromptExplain(input))
+}
+
+func (p *OllamaProvider) DetectLanguage(ctx context.Context, input string) (string, error) {
+	res, err := p.sendRequest(ctx, p.buildPromptDetectLanguage(input))
+	return strings.TrimSpace(res), err
+}
+
 type ollamaRequest struct {
-	Model  string `json:"model"`
-	Prompt string `json:"prompt"`
-	Stream bool   `json:"stream"`
+	Model  string   `json:"model"`
+	Prompt string   `json:"prompt"`
+	Images []string `json:"images,omitempty"` // base64, requires a vision model (e.g. llava)
+	Stream bool     `json:"stream"`
 }
 
 type ollamaResponse struct {
-	Model     string `json:"model"`
-	CreatedAt string `json:"created_at"`
-	Response  string `json:"response"` // This contains the token/word
-	Done      bool   `json:"done"`
+	Model           string `json:"model"`
+	CreatedAt       string `json:"created_at"`
+	Response        string `json:"response"` // This contains the token/word
+	Done            bool   `json:"done"`
+	DoneReason      string `json:"done_reason"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+}
+
+// /api/chat request, needed for tools
+type ollamaChatRequest struct {
+	Model    string              `json:"model"`
+	Messages []ollamaChatMessage `json:"messages"`
+	Tools    []openaiTool        `json:"tools,omitempty"`
+	Stream   bool                `json:"stream"`
+}
+
+type ollamaChatMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"` // role "tool"
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaChatResponse struct {
+	Model           string            `json:"model"`
+	Message         ollamaChatMessage `json:"message"`
+	Done            bool              `json:"done"`
+	DoneReason      string            `json:"done_reason"`
+	PromptEvalCount int               `json:"prompt_eval_count"`
+	EvalCount       int               `json:"eval_count"`
 }
 
 func (p *OllamaProvider) sendRequest(ctx context.Context, prompt string) (string, error) {
 
+	ctx, err := p.wait(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+
 	url := p.cfg.BaseEndpoints.Ollama
 
+	var images []string
+	for _, img := range p.images {
+		images = append(images, img.Base64())
+	}
+
 	payload := ollamaRequest{
 		Model:  p.model,
 		Prompt: prompt,
+		Images: images,
 		Stream: false,
 	}
 
+	start := time.Now()
+	var result ollamaResponse
+	if err := p.post(ctx, url, payload, &result); err != nil {
+		return "", err
+	}
+
+	recordUsage(ctx, Usage{
+		Provider:     "ollama",
+		Model:        p.model,
+		InputTokens:  result.PromptEvalCount,
+		OutputTokens: result.EvalCount,
+		FinishReason: result.DoneReason,
+		Latency:      time.Since(start),
+	})
+
+	return result.Response, nil
+}
+
+// Chat sends the conversation to /api/chat, next to the configured /api/generate endpoint
+func (p *OllamaProvider) Chat(ctx context.Context, messages []ChatMessage, tools []Tool) (ChatMessage, error) {
+	ctx, err := p.wait(ctx, chatText(messages))
+	if err != nil {
+		return ChatMessage{}, err
+	}
+
+	payload := ollamaChatRequest{Model: p.model, Stream: false}
+	for _, t := range tools {
+		payload.Tools = append(payload.Tools, openaiTool{Type: "function", Function: openaiFunction{Name: t.Name, Description: t.Description, Parameters: t.Parameters}})
+	}
+	for _, m := range messages {
+		for _, r := range m.Results {
+			payload.Messages = append(payload.Messages, ollamaChatMessage{Role: "tool", Content: resultText(r), ToolName: r.Name})
+		}
+		if len(m.Calls) == 0 && m.Text == "" {
+			continue
+		}
+		msg := ollamaChatMessage{Role: m.Role, Content: m.Text}
+		for _, c := range m.Calls {
+			var call ollamaToolCall
+			call.Function.Name = c.Name
+			call.Function.Arguments = rawArgs(c.Args)
+			msg.ToolCalls = append(msg.ToolCalls, call)
+		}
+		payload.Messages = append(payload.Messages, msg)
+	}
+
+	url := strings.Replace(p.cfg.BaseEndpoints.Ollama, "/api/generate", "/api/chat", 1)
+	start := time.Now()
+	var result ollamaChatResponse
+	if err := p.post(ctx, url, payload, &result); err != nil {
+		return ChatMessage{}, err
+	}
+
+	recordUsage(ctx, Usage{
+		Provider:     "ollama",
+		Model:        p.model,
+		InputTokens:  result.PromptEvalCount,
+		OutputTokens: result.EvalCount,
+		FinishReason: result.DoneReason,
+		Latency:      time.Since(start),
+	})
+
+	// Ollama sends no call ids
+	reply := ChatMessage{Role: RoleAssistant, Text: result.Message.Content}
+	for i, c := range result.Message.ToolCalls {
+		reply.Calls = append(reply.Calls, ToolCall{ID: fmt.Sprintf("%s-%d", c.Function.Name, i), Name: c.Function.Name, Args: c.Function.Arguments})
+	}
+	return reply, nil
+}
+
+// post sends a JSON request to the Ollama API and decodes the response into result
+func (p *OllamaProvider) post(ctx context.Context, url string, payload any, result any) error {
 	jsonBytes, err := json.Marshal(payload)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	// Create HTTP Request with Context
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBytes))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	// Execute
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("API call failed: %w", err)
+		return fmt.Errorf("API call failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("API error %d: %s", resp.StatusCode, string(bodyBytes))
+		return fmt.Errorf("API error %d: %s", resp.StatusCode, string(bodyBytes))
 	}
 
-	var result ollamaResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
 	}
-
-	return result.Response, nil
+	return nil
 }
@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"time"
 )
 
 type OpenaiProvider struct {
@@ -22,7 +24,7 @@ func NewOpenai(apiKey string, model string, cfg *config.Config) (*OpenaiProvider
 		return nil, fmt.Errorf("missing OPENAI_API_KEY environment variable")
 	}
 	return &OpenaiProvider{
-		baseProvider: baseProvider{cfg: cfg},
+		baseProvider: newBaseProvider("openai", cfg),
 		apiKey:       apiKey,
 		model:        model,
 		client:       &http.Client{},
@@ -38,81 +40,218 @@ func (p *OpenaiProvider) Translate(ctx context.Context, input string, toLanguage
 }
 
 func (p *OpenaiProvider) Summarize(ctx context.Context, input string) (string, error) {
-	return p.sendRequest(ctx, p.buildPromptSummarize(input))
+	return p.summarize(ctx, input, p.sendRequest)
 }
 
 func (p *OpenaiProvider) General(ctx context.Context, input string) (string, error) {
 	return p.sendRequest(ctx, input)
 }
 
+func (p *OpenaiProvider) Check(ctx context.Context, input string) (string, error) {
+	return p.sendRequest(ctx, p.buildPromptCheck(input))
+}
+
+func (p *OpenaiProvider) Explain(ctx context.Context, input string) (string, error) {
+	return p.sendRequest(ctx, p.buildPromptExplain(input))
+}
+
+func (p *OpenaiProvider) DetectLanguage(ctx context.Context, input string) (string, error) {
+	res, err := p.sendRequest(ctx, p.buildPromptDetectLanguage(input))
+	return strings.TrimSpace(res), err
+}
+
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role string `json:"role"`
+	// Content is a plain string, or a list of ContentPart when images are attached
+	Content    any              `json:"content"`
+	ToolCalls  []openaiToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"` // role "tool"
+}
+
+type ContentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+type ImageURL struct {
+	URL string `json:"url"`
 }
 
 type ChatRequest struct {
-	Model       string    `json:"model"`
-	Messages    []Message `json:"messages"`
-	Temperature float64   `json:"temperature"`
+	Model       string       `json:"model"`
+	Messages    []Message    `json:"messages"`
+	Temperature float64      `json:"temperature"`
+	Tools       []openaiTool `json:"tools,omitempty"`
+}
+
+// openaiTool is a function tool, the same format is used by Ollama
+type openaiTool struct {
+	Type     string         `json:"type"`
+	Function openaiFunction `json:"function"`
+}
+
+type openaiFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+type openaiToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"` // JSON object encoded as a string
+	} `json:"function"`
 }
 
 type ChatChoice struct {
 	Message struct {
-		Role    string `json:"role"`
-		Content string `json:"content"`
+		Role      string           `json:"role"`
+		Content   string           `json:"content"`
+		ToolCalls []openaiToolCall `json:"tool_calls"`
 	} `json:"message"`
 	FinishReason string `json:"finish_reason"`
 }
 
+type ChatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
 type ChatResponse struct {
 	ID      string       `json:"id"`
 	Object  string       `json:"object"`
 	Choices []ChatChoice `json:"choices"`
+	Usage   ChatUsage    `json:"usage"`
 }
 
 func (p *OpenaiProvider) sendRequest(ctx context.Context, prompt string) (string, error) {
 
+	ctx, err := p.wait(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+
+	var userContent any = prompt
+	if len(p.images) > 0 {
+		parts := []ContentPart{{Type: "text", Text: prompt}}
+		for _, img := range p.images {
+			parts = append(parts, ContentPart{Type: "image_url", ImageURL: &ImageURL{URL: img.DataURI()}})
+		}
+		userContent = parts
+	}
+
 	payload := ChatRequest{
 		Model: p.model,
 		Messages: []Message{
 			{Role: "system", Content: "You are a concise assistant."},
-			{Role: "user", Content: prompt},
+			{Role: "user", Content: userContent},
 		},
 		Temperature: p.cfg.Openai.Temperature,
 	}
 
+	result, err := p.post(ctx, payload)
+	if err != nil {
+		return "", err
+	}
+	return result.Choices[0].Message.Content, nil
+}
+
+// Chat maps the conversation to messages with tool_calls and "tool" role results
+func (p *OpenaiProvider) Chat(ctx context.Context, messages []ChatMessage, tools []Tool) (ChatMessage, error) {
+	ctx, err := p.wait(ctx, chatText(messages))
+	if err != nil {
+		return ChatMessage{}, err
+	}
+
+	payload := ChatRequest{
+		Model:       p.model,
+		Messages:    []Message{{Role: "system", Content: "You are a concise assistant."}},
+		Temperature: p.cfg.Openai.Temperature,
+	}
+	for _, t := range tools {
+		payload.Tools = append(payload.Tools, openaiTool{Type: "function", Function: openaiFunction{Name: t.Name, Description: t.Description, Parameters: t.Parameters}})
+	}
+	for _, m := range messages {
+		for _, r := range m.Results {
+			payload.Messages = append(payload.Messages, Message{Role: "tool", Content: resultText(r), ToolCallID: r.CallID})
+		}
+		if len(m.Calls) == 0 && m.Text == "" {
+			continue
+		}
+		msg := Message{Role: m.Role}
+		if m.Text != "" {
+			msg.Content = m.Text
+		}
+		for _, c := range m.Calls {
+			call := openaiToolCall{ID: c.ID, Type: "function"}
+			call.Function.Name = c.Name
+			call.Function.Arguments = string(rawArgs(c.Args))
+			msg.ToolCalls = append(msg.ToolCalls, call)
+		}
+		payload.Messages = append(payload.Messages, msg)
+	}
+
+	result, err := p.post(ctx, payload)
+	if err != nil {
+		return ChatMessage{}, err
+	}
+	choice := result.Choices[0].Message
+	reply := ChatMessage{Role: RoleAssistant, Text: choice.Content}
+	for _, c := range choice.ToolCalls {
+		reply.Calls = append(reply.Calls, ToolCall{ID: c.ID, Name: c.Function.Name, Args: json.RawMessage(c.Function.Arguments)})
+	}
+	return reply, nil
+}
+
+// post sends the request to the Chat Completions API and records its usage
+func (p *OpenaiProvider) post(ctx context.Context, payload ChatRequest) (*ChatResponse, error) {
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", p.cfg.BaseEndpoints.Openai, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+p.apiKey)
 
+	start := time.Now()
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
+		return nil, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("API error %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(bodyBytes))
 	}
 
 	var result ChatResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	if len(result.Choices) == 0 {
-		return "", fmt.Errorf("API returned empty choices")
+	usage := Usage{
+		Provider:     "openai",
+		Model:        p.model,
+		InputTokens:  result.Usage.PromptTokens,
+		OutputTokens: result.Usage.CompletionTokens,
+		Latency:      time.Since(start),
+	}
+	if len(result.Choices) > 0 {
+		usage.FinishReason = result.Choices[0].FinishReason
 	}
+	recordUsage(ctx, usage)
 
-	return result.Choices[0].Message.Content, nil
+	if len(result.Choices) == 0 {
+		return nil, fmt.Errorf("API returned empty choices")
+	}
+	return &result, nil
 }
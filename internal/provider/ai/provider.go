@@ -2,8 +2,14 @@ package ai
 
 import (
 	"ai/internal/config"
+	"ai/internal/glossary"
+	"ai/internal/ratelimit"
 	"context"
+	"encoding/base64"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 )
 
 type Provider interface {
@@ -11,20 +17,203 @@ type Provider interface {
 	Translate(ctx context.Context, text string, toLanguage string) (string, error)
 	Summarize(ctx context.Context, text string) (string, error)
 	General(ctx context.Context, text string) (string, error)
+	Check(ctx context.Context, text string) (string, error)
+	Explain(ctx context.Context, text string) (string, error)
+	DetectLanguage(ctx context.Context, text string) (string, error)
+	// Chat sends a conversation with the tools the model may call and returns its next turn
+	Chat(ctx context.Context, messages []ChatMessage, tools []Tool) (ChatMessage, error)
+	SetImages(images []Image)
+	SetRewriteOptions(opts RewriteOptions)
+	SetSummaryOptions(opts SummaryOptions)
+	SetTranslateOptions(opts TranslateOptions)
+}
+
+// TranslateOptions hold the translation settings besides the target language
+type TranslateOptions struct {
+	From         string // source language, empty to let the model infer it
+	Glossary     *glossary.Glossary
+	Instructions string // extra rules appended to the prompt
+}
+
+// RewriteOptions select the tone, style and length presets composed into the rewrite prompt
+type RewriteOptions struct {
+	Tone         string
+	Style        string
+	Length       string
+	Instructions string // extra rules added to the presets
+}
+
+// Image is an attachment sent alongside the prompt to vision-capable models
+type Image struct {
+	MimeType string
+	Data     []byte
+}
+
+func (i Image) Base64() string {
+	return base64.StdEncoding.EncodeToString(i.Data)
+}
+
+// DataURI returns the image as a data URI (data:image/png;base64,...)
+func (i Image) DataURI() string {
+	return "data:" + i.MimeType + ";base64," + i.Base64()
 }
 
 type baseProvider struct {
-	cfg *config.Config
+	cfg          *config.Config
+	images       []Image
+	rewriteOpt   RewriteOptions
+	summaryOpt   SummaryOptions
+	translateOpt TranslateOptions
+	limiter      *ratelimit.Limiter
+	maxOutput    int // output token cap of the provider, 0 when it has none
+}
+
+func newBaseProvider(name string, cfg *config.Config) baseProvider {
+	limit := cfg.RateLimits[name]
+	return baseProvider{
+		cfg:     cfg,
+		limiter: ratelimit.For(name, limit.RequestsPerMinute, limit.TokensPerMinute),
+	}
+}
+
+// wait blocks until the provider rate limit allows sending the prompt and its expected output.
+// The returned context carries the reservation, which recordUsage settles with the actual usage.
+func (b *baseProvider) wait(ctx context.Context, prompt string) (context.Context, error) {
+	if b.limiter == nil {
+		return ctx, nil
+	}
+	// Rewrites and translations answer with about as much text as they are sent
+	tokens := ratelimit.EstimateTokens(prompt)
+	output := tokens
+	if b.maxOutput > 0 {
+		output = min(output, b.maxOutput)
+	}
+	if err := b.limiter.Wait(ctx, tokens+output); err != nil {
+		return ctx, fmt.Errorf("rate limit: %w", err)
+	}
+	return context.WithValue(ctx, reservationKey{}, &reservation{limiter: b.limiter, tokens: tokens + output}), nil
+}
+
+type reservationKey struct{}
+
+// reservation is what a request took from the rate limiter before it was sent
+type reservation struct {
+	limiter *ratelimit.Limiter
+	tokens  int
+}
+
+func (b *baseProvider) SetImages(images []Image) {
+	b.images = images
+}
+
+func (b *baseProvider) SetRewriteOptions(opts RewriteOptions) {
+	b.rewriteOpt = opts
 }
 
 func (b *baseProvider) buildPromptRewrite(text string) string {
-	return b.cfg.Prompts.Rewrite + " " + text
+	// Presets go first, so the prompt still ends right before the text
+	var presets []string
+	if tone := b.cfg.Rewrite.Tones[b.rewriteOpt.Tone]; tone != "" {
+		presets = append(presets, "- Tone: "+tone)
+	}
+	if style := b.cfg.Rewrite.Styles[b.rewriteOpt.Style]; style != "" {
+		presets = append(presets, "- Style: "+style)
+	}
+	if length := b.cfg.Rewrite.Lengths[b.rewriteOpt.Length]; length != "" {
+		presets = append(presets, "- Length: "+length)
+	}
+	if b.rewriteOpt.Instructions != "" {
+		presets = append(presets, "- "+b.rewriteOpt.Instructions)
+	}
+	if len(presets) == 0 {
+		return b.cfg.Prompts.Rewrite + " " + text
+	}
+	return "Additional instructions, these take precedence over the guidelines below:\n" +
+		strings.Join(presets, "\n") + "\n\n" + b.cfg.Prompts.Rewrite + " " + text
+}
+
+func (b *baseProvider) SetTranslateOptions(opts TranslateOptions) {
+	b.translateOpt = opts
 }
 
 func (b *baseProvider) buildPromptTranslate(text, toLanguage string) string {
-	return fmt.Sprintf(b.cfg.Prompts.Translate, toLanguage) + " " + text
+	prompt := fmt.Sprintf(b.cfg.Prompts.Translate, toLanguage)
+	if b.translateOpt.From != "" {
+		prompt += " The source language is " + b.translateOpt.From + "."
+	}
+	if b.translateOpt.Instructions != "" {
+		prompt += " " + b.translateOpt.Instructions
+	}
+	if rules := b.translateOpt.Glossary.Instructions(text, toLanguage); rules != "" {
+		prompt = rules + "\n" + prompt
+	}
+	return prompt + " " + text
+}
+
+// Only the beginning of the text is needed to tell its language
+const detectLanguageSample = 1000
+
+func (b *baseProvider) buildPromptDetectLanguage(text string) string {
+	if runes := []rune(text); len(runes) > detectLanguageSample {
+		text = string(runes[:detectLanguageSample])
+	}
+	return b.cfg.Prompts.DetectLanguage + " " + text
+}
+
+func (b *baseProvider) buildPromptCheck(text string) string {
+	return b.cfg.Prompts.Check + " " + text
+}
+
+func (b *baseProvider) buildPromptExplain(text string) string {
+	return b.cfg.Prompts.Explain + "\n" + text
 }
 
-func (b *baseProvider) buildPromptSummarize(text string) string {
-	return b.cfg.Prompts.Summarize + " " + text
+// Usage is the metadata of the requests made under a tracked context
+type Usage struct {
+	Provider     string
+	Model        string
+	InputTokens  int
+	OutputTokens int
+	FinishReason string
+	Latency      time.Duration
+	Requests     int
+}
+
+type usageKey struct{}
+
+// usageTracker guards the Usage of requests sent concurrently with the same context
+type usageTracker struct {
+	mu    sync.Mutex
+	usage *Usage
+}
+
+// TrackUsage returns a context whose requests add their token usage and latency to the returned Usage.
+// The Usage must not be read before the calls made with the context have returned.
+func TrackUsage(ctx context.Context) (context.Context, *Usage) {
+	u := &Usage{}
+	return context.WithValue(ctx, usageKey{}, &usageTracker{usage: u}), u
+}
+
+// recordUsage adds a completed request to the context tracker, if any, and settles its
+// rate limit reservation when the provider reported the token usage
+func recordUsage(ctx context.Context, u Usage) {
+	if r, ok := ctx.Value(reservationKey{}).(*reservation); ok && u.InputTokens+u.OutputTokens > 0 {
+		_ = r.limiter.Settle(ctx, r.tokens, u.InputTokens+u.OutputTokens)
+	}
+
+	tracker, ok := ctx.Value(usageKey{}).(*usageTracker)
+	if !ok {
+		return
+	}
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	tracked := tracker.usage
+	tracked.Provider = u.Provider
+	tracked.Model = u.Model
+	tracked.InputTokens += u.InputTokens
+	tracked.OutputTokens += u.OutputTokens
+	tracked.FinishReason = u.FinishReason
+	tracked.Latency += u.Latency
+	tracked.Requests++
 }
@@ -0,0 +1,108 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SummaryOptions select the summary format and an optional length target
+type SummaryOptions struct {
+	Format    string
+	Words     int
+	Sentences int
+}
+
+func (b *baseProvider) SetSummaryOptions(opts SummaryOptions) {
+	b.summaryOpt = opts
+}
+
+func (b *baseProvider) buildPromptSummarize(text string) string {
+	prompt := b.cfg.Prompts.Summarize
+	if format := b.cfg.Summarize.Formats[b.summaryOpt.Format]; format != "" {
+		prompt += " " + format
+	}
+	if target := b.summaryTarget(); target != "" {
+		prompt += " The summary must be about " + target + " long."
+	}
+	return prompt + " " + text
+}
+
+func (b *baseProvider) summaryTarget() string {
+	switch {
+	case b.summaryOpt.Words > 0:
+		return fmt.Sprintf("%d words", b.summaryOpt.Words)
+	case b.summaryOpt.Sentences > 0:
+		return fmt.Sprintf("%d sentences", b.summaryOpt.Sentences)
+	}
+	return ""
+}
+
+// summarize sends the summary prompt and, when a length target is set, asks again
+// while the answer is too far off the target. The closest answer is returned.
+func (b *baseProvider) summarize(ctx context.Context, text string, send func(context.Context, string) (string, error)) (string, error) {
+	prompt := b.buildPromptSummarize(text)
+	res, err := send(ctx, prompt)
+	if err != nil || b.summaryTarget() == "" {
+		return res, err
+	}
+
+	best, bestOff := res, b.lengthOff(res)
+	for retry := 0; retry < b.cfg.Summarize.MaxRetries && bestOff > b.cfg.Summarize.TolerancePercent; retry++ {
+		feedback := fmt.Sprintf("%s\n\nA previous summary was %s long, but the target is %s:\n%s\n\nWrite the summary again with the target length.",
+			prompt, b.measure(best), b.summaryTarget(), best)
+		res, err := send(ctx, feedback)
+		if err != nil {
+			// Keep the first answer rather than failing on a retry
+			break
+		}
+		if off := b.lengthOff(res); off < bestOff {
+			best, bestOff = res, off
+		}
+	}
+	return best, nil
+}
+
+// lengthOff is the distance of the summary from the target length, in percent
+func (b *baseProvider) lengthOff(summary string) int {
+	target, actual := b.summaryOpt.Words, countWords(summary)
+	if b.summaryOpt.Words == 0 {
+		target, actual = b.summaryOpt.Sentences, countSentences(summary)
+	}
+	diff := actual - target
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff * 100 / target
+}
+
+func (b *baseProvider) measure(summary string) string {
+	if b.summaryOpt.Words > 0 {
+		return fmt.Sprintf("%d words", countWords(summary))
+	}
+	return fmt.Sprintf("%d sentences", countSentences(summary))
+}
+
+var sentenceEndRe = regexp.MustCompile(`[.!?]+(\s|$)`)
+
+func countWords(text string) int {
+	return len(strings.Fields(text))
+}
+
+// countSentences counts sentence endings, bullet lines without punctuation count as one each
+func countSentences(text string) int {
+	n := 0
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if ends := len(sentenceEndRe.FindAllString(line, -1)); ends > 0 {
+			n += ends
+		} else {
+			n++
+		}
+	}
+	return n
+}
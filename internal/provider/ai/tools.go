@@ -0,0 +1,75 @@
+package ai
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Tool is a function the model can call. Parameters is a JSON schema object, it is sent as is
+// as Claude input_schema, OpenAI and Ollama function parameters and Gemini function declarations.
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  map[string]any
+}
+
+// ToolCall is a call of a tool requested by the model, Args is a JSON object
+type ToolCall struct {
+	ID        string
+	Name      string
+	Args      json.RawMessage
+	Signature string // opaque data sent back with the call (Gemini thought signature)
+}
+
+// ToolResult is the output of a tool call sent back to the model
+type ToolResult struct {
+	CallID  string
+	Name    string
+	Output  string
+	IsError bool
+}
+
+// ChatMessage is a turn of a conversation with tools: the user text or tool results, or the
+// model text and tool calls
+type ChatMessage struct {
+	Role    string // RoleUser or RoleAssistant
+	Text    string
+	Calls   []ToolCall
+	Results []ToolResult
+}
+
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// chatText joins the text of the conversation, to estimate its tokens for the rate limiter
+func chatText(messages []ChatMessage) string {
+	var b strings.Builder
+	for _, m := range messages {
+		b.WriteString(m.Text)
+		for _, c := range m.Calls {
+			b.Write(c.Args)
+		}
+		for _, r := range m.Results {
+			b.WriteString(r.Output)
+		}
+	}
+	return b.String()
+}
+
+// rawArgs returns the arguments of a call as a JSON object, "{}" when the model sent none
+func rawArgs(args json.RawMessage) json.RawMessage {
+	if len(args) == 0 || string(args) == "null" {
+		return json.RawMessage("{}")
+	}
+	return args
+}
+
+// resultText is the output of a call for APIs without an error flag on tool results
+func resultText(r ToolResult) string {
+	if r.IsError {
+		return "Error: " + r.Output
+	}
+	return r.Output
+}
@@ -0,0 +1,213 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Limiter is a pair of token buckets allowing a number of requests and tokens per minute.
+// State is kept in the user cache directory, so limits are shared by every running process.
+// A nil Limiter never blocks.
+type Limiter struct {
+	mu                sync.Mutex
+	requestsPerMinute float64
+	tokensPerMinute   float64
+	state             state
+	statePath         string // empty when the cache directory is unavailable, limits are then per process
+}
+
+// state is the level of both buckets, persisted between processes
+type state struct {
+	Requests float64   `json:"requests"`
+	Tokens   float64   `json:"tokens"`
+	Updated  time.Time `json:"updated"`
+}
+
+// A lock file older than this is left over from a killed process
+const staleLock = 10 * time.Second
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]*Limiter{}
+)
+
+// For returns the limiter shared by all callers of a provider, or nil when both limits are
+// not positive (unlimited). Only the first call for a name decides the limits.
+func For(name string, requestsPerMinute, tokensPerMinute int) *Limiter {
+	if requestsPerMinute <= 0 && tokensPerMinute <= 0 {
+		return nil
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if l, ok := registry[name]; ok {
+		return l
+	}
+
+	l := &Limiter{
+		requestsPerMinute: float64(requestsPerMinute),
+		tokensPerMinute:   float64(tokensPerMinute),
+		state: state{
+			Requests: float64(requestsPerMinute),
+			Tokens:   float64(tokensPerMinute),
+			Updated:  time.Now(),
+		},
+	}
+	if dir, err := os.UserCacheDir(); err == nil {
+		dir = filepath.Join(dir, "ai", "ratelimit")
+		if err := os.MkdirAll(dir, 0755); err == nil {
+			l.statePath = filepath.Join(dir, name+".json")
+		}
+	}
+	registry[name] = l
+	return l
+}
+
+// Wait blocks until a request of the given estimated token count is allowed or the context is done.
+// The estimate should include the expected output, Settle corrects it once the usage is known.
+func (l *Limiter) Wait(ctx context.Context, tokens int) error {
+	if l == nil {
+		return nil
+	}
+	for {
+		delay, err := l.reserve(ctx, float64(tokens))
+		if err != nil {
+			return err
+		}
+		if delay == 0 {
+			return nil
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// EstimateTokens is a rough token count for text (~4 characters per token)
+func EstimateTokens(text string) int {
+	return len(text)/4 + 1
+}
+
+// Settle replaces the tokens reserved by Wait with the tokens the request actually used, giving
+// back what was left over or taking the excess, which delays the next requests
+func (l *Limiter) Settle(ctx context.Context, reserved, used int) error {
+	if l == nil || l.tokensPerMinute <= 0 {
+		return nil
+	}
+	return l.update(ctx, func() {
+		// Wait never takes more than the whole minute budget
+		taken := min(float64(reserved), l.tokensPerMinute)
+		l.state.Tokens = min(l.tokensPerMinute, l.state.Tokens+taken-float64(used))
+	})
+}
+
+// reserve takes from both buckets if possible, otherwise returns how long until it is
+func (l *Limiter) reserve(ctx context.Context, tokens float64) (time.Duration, error) {
+	// A single request larger than the whole minute budget waits for a full bucket
+	tokens = min(tokens, l.tokensPerMinute)
+
+	var delay time.Duration
+	err := l.update(ctx, func() {
+		if l.requestsPerMinute > 0 && l.state.Requests < 1 {
+			delay = max(delay, minutes((1-l.state.Requests)/l.requestsPerMinute))
+		}
+		if l.tokensPerMinute > 0 && l.state.Tokens < tokens {
+			delay = max(delay, minutes((tokens-l.state.Tokens)/l.tokensPerMinute))
+		}
+		if delay == 0 {
+			l.state.Requests--
+			l.state.Tokens -= tokens
+		}
+	})
+	return delay, err
+}
+
+// update refills both buckets and runs fn on them, holding the process and file locks
+func (l *Limiter) update(ctx context.Context, fn func()) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	unlock, err := l.lockFile(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	l.load()
+
+	now := time.Now()
+	elapsed := now.Sub(l.state.Updated).Minutes()
+	l.state.Requests = min(l.requestsPerMinute, l.state.Requests+elapsed*l.requestsPerMinute)
+	l.state.Tokens = min(l.tokensPerMinute, l.state.Tokens+elapsed*l.tokensPerMinute)
+	l.state.Updated = now
+
+	fn()
+	l.save()
+	return nil
+}
+
+func minutes(m float64) time.Duration {
+	return time.Duration(m * float64(time.Minute))
+}
+
+// load refreshes the state written by other processes, keeping the in-memory state if unreadable
+func (l *Limiter) load() {
+	if l.statePath == "" {
+		return
+	}
+	data, err := os.ReadFile(l.statePath)
+	if err != nil {
+		return
+	}
+	var s state
+	if err := json.Unmarshal(data, &s); err == nil && !s.Updated.IsZero() {
+		l.state = s
+	}
+}
+
+func (l *Limiter) save() {
+	if l.statePath == "" {
+		return
+	}
+	data, _ := json.Marshal(l.state)
+	_ = os.WriteFile(l.statePath, data, 0644)
+}
+
+// lockFile serializes access to the state file between processes. It uses an exclusively
+// created lock file, which works the same on every OS.
+func (l *Limiter) lockFile(ctx context.Context) (func(), error) {
+	if l.statePath == "" {
+		return func() {}, nil
+	}
+	lockPath := l.statePath + ".lock"
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { _ = os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			// Cache directory is not writable, fall back to per process limits
+			return func() {}, nil
+		}
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > staleLock {
+			_ = os.Remove(lockPath)
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package ratelimit
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newLimiter returns a limiter with full buckets, keeping its state in statePath when set
+func newLimiter(requestsPerMinute, tokensPerMinute float64, statePath string) *Limiter {
+	return &Limiter{
+		requestsPerMinute: requestsPerMinute,
+		tokensPerMinute:   tokensPerMinute,
+		state:             state{Requests: requestsPerMinute, Tokens: tokensPerMinute, Updated: time.Now()},
+		statePath:         statePath,
+	}
+}
+
+func TestReserve(t *testing.T) {
+	tests := []struct {
+		name     string
+		rpm, tpm float64
+		requests float64 // bucket levels, set ago
+		tokens   float64
+		ago      time.Duration
+		reserve  float64
+		want     time.Duration
+	}{
+		{"full buckets", 60, 1000, 60, 1000, 0, 100, 0},
+		{"no request left", 60, 0, 0, 0, 0, 100, time.Second},
+		{"half a request left", 60, 0, 0.5, 0, 0, 100, 500 * time.Millisecond},
+		{"refilled after a second", 60, 0, 0, 0, time.Second, 100, 0},
+		{"not enough tokens", 0, 600, 0, 500, 0, 600, 10 * time.Second},
+		{"tokens refilled", 0, 600, 0, 500, 10 * time.Second, 600, 0},
+		{"refill stops at the limit", 0, 600, 0, 0, time.Hour, 600, 0},
+		{"larger than the minute budget", 0, 600, 0, 0, 0, 6000, time.Minute},
+		{"longest of both waits", 60, 600, 0, 0, 0, 200, 20 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLimiter(tt.rpm, tt.tpm, "")
+			l.state = state{Requests: tt.requests, Tokens: tt.tokens, Updated: time.Now().Add(-tt.ago)}
+			got, err := l.reserve(context.Background(), tt.reserve)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Time passes between setting the state and reserving
+			if got > tt.want || got < tt.want-50*time.Millisecond {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSettle(t *testing.T) {
+	tests := []struct {
+		name           string
+		reserved, used int
+		want           float64
+	}{
+		{"unused output given back", 400, 150, 850},
+		{"larger usage taken", 400, 700, 300},
+		{"usage above the bucket", 400, 2000, -1000},
+		{"reservation capped at the budget", 5000, 500, 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLimiter(0, 1000, "")
+			if err := l.Wait(context.Background(), tt.reserved); err != nil {
+				t.Fatal(err)
+			}
+			if err := l.Settle(context.Background(), tt.reserved, tt.used); err != nil {
+				t.Fatal(err)
+			}
+			// Allow for the refill while the test runs
+			if got := l.state.Tokens; got < tt.want || got > tt.want+5 {
+				t.Errorf("got %v tokens, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWaitRefill(t *testing.T) {
+	// 1200 requests per minute refill one every 50ms
+	l := newLimiter(1200, 0, filepath.Join(t.TempDir(), "test.json"))
+	l.state.Requests = 0
+
+	start := time.Now()
+	for range 4 {
+		if err := l.Wait(context.Background(), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 190*time.Millisecond || elapsed > time.Second {
+		t.Errorf("4 requests took %v, want about 200ms", elapsed)
+	}
+}
+
+func TestWaitCanceled(t *testing.T) {
+	l := newLimiter(1, 0, "")
+	l.state.Requests = 0
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx, 1); err != context.DeadlineExceeded {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestWaitConcurrent(t *testing.T) {
+	tests := []struct {
+		name      string
+		processes int // limiters sharing the state file, as separate processes do
+	}{
+		{"one process", 1},
+		{"two processes", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statePath := filepath.Join(t.TempDir(), "test.json")
+			var limiters []*Limiter
+			for range tt.processes {
+				// 600 requests per minute refill one every 100ms, the first 5 go right away
+				l := newLimiter(600, 0, statePath)
+				l.state.Requests = 5
+				limiters = append(limiters, l)
+			}
+
+			start := time.Now()
+			var wg sync.WaitGroup
+			for i := range 10 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if err := limiters[i%len(limiters)].Wait(context.Background(), 1); err != nil {
+						t.Error(err)
+					}
+				}()
+			}
+			wg.Wait()
+
+			// The other 5 requests wait for the refill
+			if elapsed := time.Since(start); elapsed < 450*time.Millisecond || elapsed > 2*time.Second {
+				t.Errorf("10 requests took %v, want about 500ms", elapsed)
+			}
+		})
+	}
+}
+
+func TestFor(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	if l := For("test-unlimited", 0, 0); l != nil {
+		t.Errorf("For without limits = %v, want nil", l)
+	}
+	if err := (*Limiter)(nil).Wait(context.Background(), 100); err != nil {
+		t.Errorf("nil Wait = %v", err)
+	}
+	a, b := For("test-shared", 60, 0), For("test-shared", 1, 1)
+	if a != b || a.requestsPerMinute != 60 {
+		t.Errorf("For returned %p and %p, want the first limiter for both", a, b)
+	}
+}
@@ -0,0 +1,186 @@
+package review
+
+import (
+	"ai/internal/git"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Severities of a finding, as in compiler diagnostics and SARIF levels
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityNote    = "note"
+)
+
+// Finding is a problem the model reported on a line of the new version of a file
+type Finding struct {
+	File       string `json:"file"`
+	Line       int    `json:"line"`
+	Severity   string `json:"severity"`
+	Category   string `json:"category"`
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// Prompt composes the review request for one chunk of the annotated diff
+func Prompt(template, instructions, chunk string) string {
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(template, "\n") + "\n")
+	if instructions = strings.TrimSpace(instructions); instructions != "" {
+		b.WriteString("\nFocus of the review:\n" + instructions + "\n")
+	}
+	b.WriteString("\n" + chunk)
+	return b.String()
+}
+
+var hunkRe = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// annotate prefixes the lines of a file diff with their line number in the new version, so the
+// model does not have to count. It returns the lines and the numbers findings can point at.
+func annotate(f git.FileDiff) ([]string, map[int]bool) {
+	var lines []string
+	numbers := map[int]bool{}
+	n, inHunk := 0, false
+	for _, line := range strings.Split(strings.TrimRight(f.Diff, "\n"), "\n") {
+		if m := hunkRe.FindStringSubmatch(line); m != nil {
+			n, _ = strconv.Atoi(m[1])
+			inHunk = true
+			lines = append(lines, line)
+			continue
+		}
+		if !inHunk {
+			// Keep the file status (new, deleted, renamed, binary), the path is in the part header
+			if !strings.HasPrefix(line, "diff --git ") && !strings.HasPrefix(line, "index ") &&
+				!strings.HasPrefix(line, "--- ") && !strings.HasPrefix(line, "+++ ") {
+				lines = append(lines, line)
+			}
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "-"):
+			lines = append(lines, "      "+line)
+		case strings.HasPrefix(line, "+"), strings.HasPrefix(line, " "), line == "":
+			if line == "" {
+				line = " "
+			}
+			lines = append(lines, fmt.Sprintf("%5d %s", n, line))
+			numbers[n] = true
+			n++
+		}
+	}
+	return lines, numbers
+}
+
+// Chunks packs the annotated diffs of the files into pieces of at most limit bytes. A file too
+// large for one piece is split at line boundaries and labeled with its part number.
+func Chunks(files []git.FileDiff, limit int) []string {
+	var chunks []string
+	var cur strings.Builder
+	for _, f := range files {
+		lines, _ := annotate(f)
+		for _, part := range parts(f.Path, lines, limit) {
+			if cur.Len() > 0 && cur.Len()+len(part) > limit {
+				chunks = append(chunks, cur.String())
+				cur.Reset()
+			}
+			cur.WriteString(part)
+		}
+	}
+	if cur.Len() > 0 {
+		chunks = append(chunks, cur.String())
+	}
+	return chunks
+}
+
+func parts(path string, lines []string, limit int) []string {
+	header := "=== " + path + " ===\n"
+	body := strings.Join(lines, "\n") + "\n"
+	if len(header)+len(body) <= limit {
+		return []string{header + body}
+	}
+
+	var bodies []string
+	var cur strings.Builder
+	budget := limit - len(header) - 16 // room for the part number
+	for _, line := range lines {
+		if cur.Len() > 0 && cur.Len()+len(line)+1 > budget {
+			bodies = append(bodies, cur.String())
+			cur.Reset()
+		}
+		cur.WriteString(line + "\n")
+	}
+	if cur.Len() > 0 {
+		bodies = append(bodies, cur.String())
+	}
+	res := make([]string, len(bodies))
+	for i, b := range bodies {
+		res[i] = fmt.Sprintf("=== %s (part %d/%d) ===\n%s", path, i+1, len(bodies), b)
+	}
+	return res
+}
+
+// Parse reads the JSON array of findings from a model reply, ignoring any text or code fence around it
+func Parse(reply string) ([]Finding, error) {
+	start := strings.Index(reply, "[")
+	end := strings.LastIndex(reply, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no finding list in response")
+	}
+
+	var findings []Finding
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &findings); err != nil {
+		return nil, fmt.Errorf("invalid finding list: %w", err)
+	}
+	return findings, nil
+}
+
+// Validate keeps the findings anchored on a line shown in the diff of their file and normalizes
+// their severity. Findings on other lines or files are dropped.
+func Validate(files []git.FileDiff, findings []Finding) (valid []Finding, dropped int) {
+	numbers := map[string]map[int]bool{}
+	for _, f := range files {
+		_, numbers[f.Path] = annotate(f)
+	}
+	for _, finding := range findings {
+		finding.File = strings.TrimPrefix(finding.File, "b/")
+		if !numbers[finding.File][finding.Line] || strings.TrimSpace(finding.Message) == "" {
+			dropped++
+			continue
+		}
+		finding.Severity = severity(finding.Severity)
+		if finding.Category == "" {
+			finding.Category = "review"
+		}
+		valid = append(valid, finding)
+	}
+	return valid, dropped
+}
+
+// severity maps the words models use to error, warning or note
+func severity(s string) string {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "error", "critical", "blocker", "high", "major":
+		return SeverityError
+	case "note", "info", "low", "minor", "nit", "suggestion":
+		return SeverityNote
+	default:
+		return SeverityWarning
+	}
+}
+
+// Format prints findings like compiler diagnostics: file:line: severity: message [category]
+func Format(findings []Finding) string {
+	var sb strings.Builder
+	for _, f := range findings {
+		fmt.Fprintf(&sb, "%s:%d: %s: %s [%s]", f.File, f.Line, f.Severity, f.Message, f.Category)
+		if f.Suggestion != "" {
+			fmt.Fprintf(&sb, " (suggestion: %s)", f.Suggestion)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
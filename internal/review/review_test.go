@@ -0,0 +1,136 @@
+package review
+
+import (
+	"ai/internal/git"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+var files = []git.FileDiff{
+	{Path: "main.go", Diff: "diff --git a/main.go b/main.go\nindex 1111111..2222222 100644\n--- a/main.go\n+++ b/main.go\n@@ -10,3 +10,3 @@ func main() {\n \tx := 1\n-\tprintln(x)\n+\tprintln(x + 1)\n \n"},
+	{Path: "new.txt", Diff: "diff --git a/new.txt b/new.txt\nnew file mode 100644\n--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1,2 @@\n+one\n+two\n"},
+}
+
+func TestAnnotate(t *testing.T) {
+	lines, numbers := annotate(files[0])
+	want := "@@ -10,3 +10,3 @@ func main() {\n   10  \tx := 1\n      -\tprintln(x)\n   11 +\tprintln(x + 1)\n   12  "
+	if got := strings.Join(lines, "\n"); got != want {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+	if len(numbers) != 3 || !numbers[10] || !numbers[11] || !numbers[12] {
+		t.Errorf("numbers = %v", numbers)
+	}
+
+	lines, _ = annotate(files[1])
+	if lines[0] != "new file mode 100644" {
+		t.Errorf("file status dropped: %q", lines)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		finding  Finding
+		severity string
+		dropped  bool
+	}{
+		{"added line", Finding{File: "main.go", Line: 11, Severity: "critical", Message: "off by one"}, SeverityError, false},
+		{"context line", Finding{File: "b/main.go", Line: 10, Severity: "nit", Message: "name"}, SeverityNote, false},
+		{"new file", Finding{File: "new.txt", Line: 2, Message: "typo"}, SeverityWarning, false},
+		{"line outside the diff", Finding{File: "main.go", Line: 3, Message: "unrelated"}, "", true},
+		{"file outside the diff", Finding{File: "other.go", Line: 11, Message: "unrelated"}, "", true},
+		{"no message", Finding{File: "main.go", Line: 11, Message: " "}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid, dropped := Validate(files, []Finding{tt.finding})
+			if tt.dropped {
+				if dropped != 1 || len(valid) != 0 {
+					t.Errorf("got %v (%d dropped), want the finding dropped", valid, dropped)
+				}
+				return
+			}
+			if len(valid) != 1 {
+				t.Fatal("finding dropped")
+			}
+			if got := valid[0]; got.Severity != tt.severity || got.Category != "review" || strings.HasPrefix(got.File, "b/") {
+				t.Errorf("got %+v", got)
+			}
+		})
+	}
+}
+
+func TestChunks(t *testing.T) {
+	if chunks := Chunks(files, 1000); len(chunks) != 1 || !strings.HasPrefix(chunks[0], "=== main.go ===\n") || !strings.Contains(chunks[0], "=== new.txt ===\n") {
+		t.Errorf("small diffs should share a chunk: %q", chunks)
+	}
+
+	// A file larger than the limit is split into numbered parts
+	var diff strings.Builder
+	diff.WriteString("@@ -1,40 +1,40 @@\n")
+	for i := 0; i < 40; i++ {
+		diff.WriteString("+a line of code\n")
+	}
+	chunks := Chunks([]git.FileDiff{{Path: "big.go", Diff: diff.String()}}, 200)
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunks", len(chunks))
+	}
+	for i, c := range chunks {
+		if len(c) > 200 {
+			t.Errorf("chunk %d has %d bytes", i, len(c))
+		}
+		if !strings.HasPrefix(c, "=== big.go (part ") {
+			t.Errorf("chunk %d is not labeled: %q", i, c)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		reply   string
+		want    int
+		wantErr bool
+	}{
+		{`[{"file":"main.go","line":11,"message":"bug"}]`, 1, false},
+		{"```json\n[]\n```", 0, false},
+		{"Looks good to me.", 0, true},
+		{`[{"file":}]`, 0, true},
+	}
+	for _, tt := range tests {
+		findings, err := Parse(tt.reply)
+		if (err != nil) != tt.wantErr || len(findings) != tt.want {
+			t.Errorf("Parse(%q) = %v, %v", tt.reply, findings, err)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	findings := []Finding{
+		{File: "main.go", Line: 11, Severity: SeverityError, Category: "bug", Message: "off by one", Suggestion: "use x"},
+		{File: "new.txt", Line: 2, Severity: SeverityNote, Category: "style", Message: "typo"},
+	}
+	want := "main.go:11: error: off by one [bug] (suggestion: use x)\nnew.txt:2: note: typo [style]\n"
+	if got := Format(findings); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	data, err := SARIF(findings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatal(err)
+	}
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != 2 || run.Tool.Driver.Rules[0].ID != "bug" || len(run.Results) != 2 {
+		t.Fatalf("got %s", data)
+	}
+	if r := run.Results[0]; r.Level != "error" || r.Locations[0].PhysicalLocation.Region.StartLine != 11 || r.Message.Text != "off by one\nSuggestion: use x" {
+		t.Errorf("got %+v", r)
+	}
+	if data, _ := SARIF(nil); !strings.Contains(string(data), `"results": []`) {
+		t.Errorf("no findings should give an empty result list: %s", data)
+	}
+}
@@ -0,0 +1,88 @@
+package review
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// SARIF 2.1.0 log with the subset of fields code scanning tools read
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region struct {
+			StartLine int `json:"startLine"`
+		} `json:"region"`
+	} `json:"physicalLocation"`
+}
+
+// SARIF returns the findings as a SARIF log, categories become rules
+func SARIF(findings []Finding) ([]byte, error) {
+	results := []sarifResult{}
+	categories := map[string]bool{}
+	for _, f := range findings {
+		text := f.Message
+		if f.Suggestion != "" {
+			text += "\nSuggestion: " + f.Suggestion
+		}
+		var loc sarifLocation
+		loc.PhysicalLocation.ArtifactLocation.URI = f.File
+		loc.PhysicalLocation.Region.StartLine = f.Line
+		results = append(results, sarifResult{
+			RuleID:    f.Category,
+			Level:     f.Severity,
+			Message:   sarifMessage{Text: text},
+			Locations: []sarifLocation{loc},
+		})
+		categories[f.Category] = true
+	}
+
+	rules := []sarifRule{}
+	for id := range categories {
+		rules = append(rules, sarifRule{ID: id})
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: sarifDriver{Name: "ai review", Rules: rules}}, Results: results}},
+	}
+	return json.MarshalIndent(log, "", "  ")
+}
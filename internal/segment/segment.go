@@ -0,0 +1,50 @@
+package segment
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Batch joins texts for a single request, each prefixed with a [[n]] marker line
+func Batch(texts []string) string {
+	var sb strings.Builder
+	for i, text := range texts {
+		fmt.Fprintf(&sb, "[[%d]]\n%s\n", i+1, text)
+	}
+	return sb.String()
+}
+
+var markerRe = regexp.MustCompile(`(?m)^\s*\[\[(\d+)\]\][ \t]*\n?`)
+
+// Unbatch splits a reply back into n texts, failing if any marker is missing or repeated
+func Unbatch(reply string, n int) ([]string, error) {
+	texts := make([]string, n)
+	found := make([]bool, n)
+
+	locs := markerRe.FindAllStringSubmatchIndex(reply, -1)
+	for i, loc := range locs {
+		idx, _ := strconv.Atoi(reply[loc[2]:loc[3]])
+		if idx < 1 || idx > n || found[idx-1] {
+			return nil, fmt.Errorf("unexpected marker %d", idx)
+		}
+		end := len(reply)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		text := strings.TrimSpace(reply[loc[1]:end])
+		if text == "" {
+			return nil, fmt.Errorf("empty text for marker %d", idx)
+		}
+		texts[idx-1] = text
+		found[idx-1] = true
+	}
+
+	for i, ok := range found {
+		if !ok {
+			return nil, fmt.Errorf("missing marker %d", i+1)
+		}
+	}
+	return texts, nil
+}
@@ -0,0 +1,48 @@
+package segment
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBatchRoundTrip(t *testing.T) {
+	texts := []string{"First text.", "Two\nlines", "Third [[4]] inline"}
+	got, err := Unbatch(Batch(texts), len(texts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, texts) {
+		t.Errorf("got %q, want %q", got, texts)
+	}
+}
+
+func TestUnbatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   string
+		n       int
+		want    []string
+		wantErr bool
+	}{
+		{"in order", "[[1]]\nEins\n[[2]]\nZwei\n", 2, []string{"Eins", "Zwei"}, false},
+		{"out of order", "[[2]]\nZwei\n[[1]]\nEins", 2, []string{"Eins", "Zwei"}, false},
+		{"marker on the text line", "[[1]] Eins\n[[2]] Zwei", 2, []string{"Eins", "Zwei"}, false},
+		{"text around", "Here you go:\n[[1]]\nEins\n\n[[2]]\nZwei\n\n", 2, []string{"Eins", "Zwei"}, false},
+		{"missing marker", "[[1]]\nEins\n", 2, nil, true},
+		{"repeated marker", "[[1]]\nEins\n[[1]]\nZwei", 2, nil, true},
+		{"unknown marker", "[[1]]\nEins\n[[3]]\nDrei", 2, nil, true},
+		{"empty text", "[[1]]\n\n[[2]]\nZwei", 2, nil, true},
+		{"no markers", "Eins Zwei", 2, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Unbatch(tt.reply, tt.n)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package shell
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Suggestion is the command proposed by the model. Command is empty when the model only answers.
+type Suggestion struct {
+	Command     string `json:"command"`
+	Explanation string `json:"explanation"`
+}
+
+// Turn is an executed command and its output, sent back for a follow-up request
+type Turn struct {
+	Request  string
+	Command  string
+	ExitCode int
+	Output   string
+}
+
+// Name returns the shell commands run with: $SHELL, /bin/sh, or cmd on Windows
+func Name() string {
+	if runtime.GOOS == "windows" {
+		return "cmd"
+	}
+	if sh := os.Getenv("SHELL"); sh != "" {
+		return sh
+	}
+	return "/bin/sh"
+}
+
+// Command returns the process running the command line in the shell
+func Command(line string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", line)
+	}
+	return exec.Command(Name(), "-c", line)
+}
+
+// Prompt composes the request with the environment the command runs in and the previous turns
+func Prompt(template, request string, history []Turn) string {
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(template, "\n") + "\n\n")
+	cwd, _ := os.Getwd()
+	fmt.Fprintf(&b, "Shell: %s\nOS: %s\nWorking directory: %s\n", filepath.Base(Name()), runtime.GOOS, cwd)
+	for _, t := range history {
+		fmt.Fprintf(&b, "\nRequest: %s\nCommand: %s\nExit status: %d\nOutput:\n%s\n", t.Request, t.Command, t.ExitCode, strings.TrimRight(t.Output, "\n"))
+	}
+	b.WriteString("\nRequest: " + request + "\n")
+	return b.String()
+}
+
+// Parse reads the JSON object of a model reply, ignoring any text or code fence around it
+func Parse(reply string) (Suggestion, error) {
+	var s Suggestion
+	start := strings.Index(reply, "{")
+	end := strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return s, fmt.Errorf("no command in response")
+	}
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &s); err != nil {
+		return s, fmt.Errorf("invalid command response: %w", err)
+	}
+	s.Command = strings.TrimSpace(s.Command)
+	s.Explanation = strings.TrimSpace(s.Explanation)
+	if s.Command == "" && s.Explanation == "" {
+		return s, fmt.Errorf("empty command response")
+	}
+	return s, nil
+}
+
+// Denylist matches destructive commands that must be typed back before they run
+type Denylist []*regexp.Regexp
+
+// NewDenylist compiles the patterns of the config
+func NewDenylist(patterns []string) (Denylist, error) {
+	var d Denylist
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid denylist pattern %q: %w", p, err)
+		}
+		d = append(d, re)
+	}
+	return d, nil
+}
+
+// Match returns the first pattern matching the command, or ""
+func (d Denylist) Match(command string) string {
+	for _, re := range d {
+		if re.MatchString(command) {
+			return re.String()
+		}
+	}
+	return ""
+}
+
+// TailBuffer keeps the last Limit bytes written to it, the end of an output tells the most.
+// It is safe to write from the stdout and stderr copy goroutines at the same time.
+type TailBuffer struct {
+	Limit int
+	mu    sync.Mutex
+	data  []byte
+	cut   bool
+}
+
+func (t *TailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.data = append(t.data, p...)
+	if len(t.data) > 2*t.Limit {
+		t.data = append([]byte{}, t.data[len(t.data)-t.Limit:]...)
+		t.cut = true
+	}
+	return len(p), nil
+}
+
+func (t *TailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	data, cut := t.data, t.cut
+	if len(data) > t.Limit {
+		data, cut = data[len(data)-t.Limit:], true
+	}
+	if cut {
+		return "[... output cut ...]\n" + string(data)
+	}
+	return string(data)
+}
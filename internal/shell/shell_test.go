@@ -0,0 +1,106 @@
+package shell
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		reply   string
+		want    Suggestion
+		wantErr bool
+	}{
+		{`{"command": " ls -la ", "explanation": "lists files"}`, Suggestion{Command: "ls -la", Explanation: "lists files"}, false},
+		{"```json\n{\"command\": \"\", \"explanation\": \"No command needed.\"}\n```", Suggestion{Explanation: "No command needed."}, false},
+		{"Run ls.", Suggestion{}, true},
+		{`{"command": ""}`, Suggestion{}, true},
+		{`{"command": }`, Suggestion{}, true},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.reply)
+		if (err != nil) != tt.wantErr || err == nil && got != tt.want {
+			t.Errorf("Parse(%q) = %+v, %v", tt.reply, got, err)
+		}
+	}
+}
+
+func TestDenylist(t *testing.T) {
+	d, err := NewDenylist([]string{`\brm\s+(-\S+\s+)*-[a-zA-Z]*[rRf]`, `\bdd\b`, `>\s*/dev/(sd|nvme)`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		command string
+		denied  bool
+	}{
+		{"rm -rf build", true},
+		{"rm -v -f file", true},
+		{"rm file", false},
+		{"dd if=/dev/zero of=x", true},
+		{"echo add", false},
+		{"cat img > /dev/sda", true},
+		{"ls -la", false},
+	}
+	for _, tt := range tests {
+		if got := d.Match(tt.command); (got != "") != tt.denied {
+			t.Errorf("Match(%q) = %q", tt.command, got)
+		}
+	}
+	if _, err := NewDenylist([]string{"("}); err == nil {
+		t.Error("an invalid pattern should fail")
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	tests := []struct {
+		writes []string
+		want   string
+	}{
+		{[]string{"short"}, "short"},
+		{[]string{"0123456789"}, "0123456789"},
+		{[]string{"01234", "56789", "abc"}, "[... output cut ...]\n3456789abc"},
+		{[]string{strings.Repeat("x", 25), "end"}, "[... output cut ...]\nxxxxxxxend"},
+	}
+	for _, tt := range tests {
+		b := &TailBuffer{Limit: 10}
+		for _, w := range tt.writes {
+			if n, err := b.Write([]byte(w)); n != len(w) || err != nil {
+				t.Fatalf("Write = %d, %v", n, err)
+			}
+		}
+		if got := b.String(); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.writes, got, tt.want)
+		}
+	}
+}
+
+func TestTailBufferConcurrent(t *testing.T) {
+	// stdout and stderr are copied by two goroutines, run with -race
+	b := &TailBuffer{Limit: 100}
+	var wg sync.WaitGroup
+	for _, s := range []string{"out\n", "err\n"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				b.Write([]byte(s))
+				_ = b.String()
+			}
+		}()
+	}
+	wg.Wait()
+	if got := strings.TrimPrefix(b.String(), "[... output cut ...]\n"); len(got) != 100 {
+		t.Errorf("kept %d bytes, want 100", len(got))
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	got := Prompt("Template\n", "count files", []Turn{{Request: "list files", Command: "ls", ExitCode: 0, Output: "a\nb\n"}})
+	for _, want := range []string{"Template\n\nShell: ", "\nRequest: list files\nCommand: ls\nExit status: 0\nOutput:\na\nb\n", "\nRequest: count files\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("prompt misses %q:\n%s", want, got)
+		}
+	}
+}
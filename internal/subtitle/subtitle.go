@@ -0,0 +1,142 @@
+package subtitle
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Block is a cue or any other block of the file (WEBVTT header, NOTE, STYLE),
+// only the Text of cues is ever changed
+type Block struct {
+	Raw    []string // lines of a non-cue block, kept verbatim
+	Header []string // cue identifier (SRT index or VTT id) and timing line, kept verbatim
+	Text   []string // cue text lines
+	sep    string   // blank lines following the block
+}
+
+func (b Block) IsCue() bool {
+	return b.Header != nil
+}
+
+// File is a parsed SRT or WebVTT subtitle file
+type File struct {
+	Blocks  []Block
+	newline string
+	hasBOM  bool
+	lead    string // newlines before the first block and after the last one
+	trail   string
+}
+
+// IsSubtitle reports whether the path has a supported subtitle extension
+func IsSubtitle(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".srt" || ext == ".vtt"
+}
+
+const bom = "\uFEFF"
+
+var blankLinesRe = regexp.MustCompile(`\n([ \t]*\n)+`)
+
+// Parse reads SRT or WebVTT content. Blocks are separated by blank lines, a block
+// with a "-->" timing line is a cue.
+func Parse(data string) (*File, error) {
+	f := &File{newline: "\n"}
+	if strings.HasPrefix(data, bom) {
+		f.hasBOM = true
+		data = strings.TrimPrefix(data, bom)
+	}
+	if strings.Contains(data, "\r\n") {
+		f.newline = "\r\n"
+		data = strings.ReplaceAll(data, "\r\n", "\n")
+	}
+
+	body := strings.TrimLeft(data, "\n")
+	f.lead = data[:len(data)-len(body)]
+	body = strings.TrimRight(body, "\n")
+	f.trail = data[len(f.lead)+len(body):]
+
+	// Runs of blank lines separate the blocks, they are kept to re-emit the file as it was
+	var chunks, seps []string
+	prev := 0
+	for _, loc := range blankLinesRe.FindAllStringIndex(body, -1) {
+		chunks = append(chunks, body[prev:loc[0]])
+		seps = append(seps, body[loc[0]:loc[1]])
+		prev = loc[1]
+	}
+	chunks = append(chunks, body[prev:])
+	seps = append(seps, "")
+
+	for n, chunk := range chunks {
+		lines := strings.Split(chunk, "\n")
+		timing := -1
+		for i, line := range lines {
+			if strings.Contains(line, "-->") {
+				timing = i
+				break
+			}
+		}
+		// NOTE blocks may contain "-->" as well, and never are cues
+		if timing < 0 || timing > 1 || strings.HasPrefix(lines[0], "NOTE") {
+			f.Blocks = append(f.Blocks, Block{Raw: lines, sep: seps[n]})
+			continue
+		}
+		f.Blocks = append(f.Blocks, Block{Header: lines[:timing+1], Text: lines[timing+1:], sep: seps[n]})
+	}
+
+	if len(f.Cues()) == 0 {
+		return nil, fmt.Errorf("no subtitle cues found")
+	}
+	return f, nil
+}
+
+// Cues returns the indexes of the cue blocks
+func (f *File) Cues() []int {
+	var cues []int
+	for i, b := range f.Blocks {
+		if b.IsCue() {
+			cues = append(cues, i)
+		}
+	}
+	return cues
+}
+
+// Clone returns a copy whose cue texts can be replaced without changing f
+func (f *File) Clone() *File {
+	c := *f
+	c.Blocks = append([]Block(nil), f.Blocks...)
+	return &c
+}
+
+// Text returns the cue text of the whole file, used to detect its language
+func (f *File) Text() string {
+	var parts []string
+	for _, i := range f.Cues() {
+		parts = append(parts, strings.Join(f.Blocks[i].Text, "\n"))
+	}
+	return strings.Join(parts, "\n")
+}
+
+// String re-emits the file with the original line endings and blank lines
+func (f *File) String() string {
+	var sb strings.Builder
+	sb.WriteString(f.lead)
+	for _, b := range f.Blocks {
+		if b.IsCue() {
+			sb.WriteString(strings.Join(append(append([]string{}, b.Header...), b.Text...), "\n"))
+		} else {
+			sb.WriteString(strings.Join(b.Raw, "\n"))
+		}
+		sb.WriteString(b.sep)
+	}
+	sb.WriteString(f.trail)
+	out := sb.String()
+	if f.newline != "\n" {
+		out = strings.ReplaceAll(out, "\n", f.newline)
+	}
+	if f.hasBOM {
+		out = bom + out
+	}
+	return out
+}
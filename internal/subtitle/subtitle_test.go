@@ -0,0 +1,127 @@
+package subtitle
+
+import (
+	"strings"
+	"testing"
+)
+
+const srt = `1
+00:00:01,000 --> 00:00:02,500
+Hello there.
+
+2
+00:00:03,000 --> 00:00:04,000
+Two lines
+of text.
+`
+
+const vtt = `WEBVTT
+Kind: captions
+
+STYLE
+::cue { color: yellow }
+
+NOTE a comment with --> inside
+
+intro
+00:00:01.000 --> 00:00:02.000 align:start position:10%
+<v Roger>Hello</v>
+
+00:00:03.000 --> 00:00:04.000
+Bye
+`
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		cues int
+	}{
+		{"srt", srt, 2},
+		{"vtt", vtt, 2},
+		{"crlf", strings.ReplaceAll(srt, "\n", "\r\n"), 2},
+		{"bom", bom + srt, 2},
+		{"extra blank lines", "\n1\n00:00:01,000 --> 00:00:02,000\nA\n\n \n\n2\n00:00:03,000 --> 00:00:04,000\nB\n\n", 2},
+		{"no final newline", "1\n00:00:01,000 --> 00:00:02,000\nA", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Parse(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(f.Cues()); got != tt.cues {
+				t.Errorf("got %d cues, want %d", got, tt.cues)
+			}
+			if got := f.String(); got != tt.data {
+				t.Errorf("round trip changed the file:\n%q\nwant\n%q", got, tt.data)
+			}
+		})
+	}
+}
+
+func TestReplaceText(t *testing.T) {
+	for name, data := range map[string]string{"srt": srt, "vtt": vtt, "crlf": strings.ReplaceAll(vtt, "\n", "\r\n")} {
+		t.Run(name, func(t *testing.T) {
+			f, err := Parse(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := f.Clone()
+			for _, i := range out.Cues() {
+				out.Blocks[i].Text = []string{"translated"}
+			}
+
+			// Only the cue text changes, headers and timings are kept byte for byte
+			want := data
+			for _, i := range f.Cues() {
+				nl := "\n"
+				if strings.Contains(data, "\r\n") {
+					nl = "\r\n"
+				}
+				header := strings.Join(f.Blocks[i].Header, nl) + nl
+				want = strings.Replace(want, header+strings.Join(f.Blocks[i].Text, nl), header+"translated", 1)
+			}
+			if got := out.String(); got != want {
+				t.Errorf("got\n%q\nwant\n%q", got, want)
+			}
+			if f.String() != data {
+				t.Error("replacing the text of the clone changed the original")
+			}
+		})
+	}
+}
+
+func TestParseBlocks(t *testing.T) {
+	f, err := Parse(vtt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []string
+	for _, b := range f.Blocks {
+		if b.IsCue() {
+			kinds = append(kinds, "cue:"+b.Header[len(b.Header)-1][:12])
+		} else {
+			kinds = append(kinds, "raw:"+b.Raw[0])
+		}
+	}
+	want := "raw:WEBVTT raw:STYLE raw:NOTE a comment with --> inside cue:00:00:01.000 cue:00:00:03.000"
+	if got := strings.Join(kinds, " "); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if got := f.Text(); got != "<v Roger>Hello</v>\nBye" {
+		t.Errorf("Text() = %q", got)
+	}
+
+	if _, err := Parse("WEBVTT\n\nNOTE only a note\n"); err == nil {
+		t.Error("a file without cues should fail")
+	}
+}
+
+func TestIsSubtitle(t *testing.T) {
+	for path, want := range map[string]bool{"a.srt": true, "b.VTT": true, "c.txt": false, "srt": false} {
+		if got := IsSubtitle(path); got != want {
+			t.Errorf("IsSubtitle(%q) = %v", path, got)
+		}
+	}
+}